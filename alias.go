@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// AliasGVK makes Get and List of the GVK from, when it has no store, read the store of the
+// GVK to instead, e.g. when a kind is known under several names. The objects are returned
+// as objects of to. Aliases are not followed transitively.
+func (s *CacheStores) AliasGVK(from, to schema.GroupVersionKind) error {
+	if from == to {
+		return fmt.Errorf("cannot alias %s to itself", from)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.aliases == nil {
+		s.aliases = make(map[schema.GroupVersionKind]schema.GroupVersionKind)
+	}
+	s.aliases[from] = to
+
+	return nil
+}
+
+// resolveAlias returns the GVK the given one is aliased to if it has no store, and the
+// given GVK otherwise.
+func (s *CacheStores) resolveAlias(gvk schema.GroupVersionKind) schema.GroupVersionKind {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.storesByGvk[gvk] != nil {
+		return gvk
+	}
+	if to, aliased := s.aliases[gvk]; aliased {
+		return to
+	}
+
+	return gvk
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestAliasGVK(t *testing.T) {
+	c := newTestCache(t)
+	mustAdd(t, c, deployment("default", "web", nil))
+
+	// the plural is a common mistake for the kind.
+	from := appsv1.SchemeGroupVersion.WithKind("Deployments")
+	to := appsv1.SchemeGroupVersion.WithKind("Deployment")
+	aliased := &unstructured.Unstructured{}
+	aliased.SetGroupVersionKind(from)
+	aliased.SetNamespace("default")
+	aliased.SetName("web")
+
+	if _, _, err := c.Get(aliased); !errors.Is(err, ErrGvkNotFound) {
+		t.Fatalf("got error %v before the alias, want ErrGvkNotFound", err)
+	}
+	if err := c.AliasGVK(from, to); err != nil {
+		t.Fatalf("failed to alias %s: %v", from, err)
+	}
+
+	item, exists, err := c.Get(aliased)
+	if err != nil || !exists {
+		t.Fatalf("failed to get the deployment through the alias: exists %v, err %v", exists, err)
+	}
+	if gvk := item.(*unstructured.Unstructured).GroupVersionKind(); gvk != to {
+		t.Errorf("got GVK %s, want %s", gvk, to)
+	}
+
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(from)
+	if err := c.Reader().Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "web"}, out); err != nil {
+		t.Errorf("failed to read the deployment through the alias: %v", err)
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(from.GroupVersion().WithKind(from.Kind + "List"))
+	if err := c.List(list); err != nil || len(list.Items) != 1 {
+		t.Errorf("failed to list the deployments through the alias: items %d, err %v", len(list.Items), err)
+	}
+
+	if err := c.AliasGVK(to, to); err == nil {
+		t.Error("expected an error aliasing a GVK to itself")
+	}
+	// a GVK with its own store is not aliased.
+	if got := c.resolveAlias(to); got != to {
+		t.Errorf("got %s for a registered GVK, want %s", got, to)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AssertContains fails the test unless each of the given objects is cached and equal to the
+// cached object, reporting the differences otherwise. Resource versions are ignored, as the
+// cache bumps them on updates, and so is the TypeMeta of the expected objects, which Get
+// always sets.
+func AssertContains(t testing.TB, c *CacheStores, objs ...client.Object) {
+	t.Helper()
+
+	for _, expected := range objs {
+		if expected == nil {
+			t.Errorf("cannot assert nil object is cached")
+			continue
+		}
+		key := client.ObjectKeyFromObject(expected)
+
+		gvk, err := c.gvkFor(expected)
+		if err != nil {
+			t.Errorf("failed to get %s: %v", key, err)
+			continue
+		}
+
+		item, exists, err := c.Get(expected)
+		if err != nil {
+			t.Errorf("failed to get %s %s: %v", gvk.Kind, key, err)
+			continue
+		}
+		if !exists {
+			t.Errorf("%s %s is not cached", gvk.Kind, key)
+			continue
+		}
+
+		cached, ok := item.(client.Object)
+		if !ok {
+			t.Errorf("cache contained %T, which is not a client.Object", item)
+			continue
+		}
+		cached = cached.DeepCopyObject().(client.Object)
+		cached.SetResourceVersion("")
+		want := withGVK(expected, *gvk)
+		want.SetResourceVersion("")
+
+		if diff := cmp.Diff(want, cached); diff != "" {
+			t.Errorf("cached %s %s differs (-want +got):\n%s", gvk.Kind, key, diff)
+		}
+	}
+}
+
+// recordingTB records the failures reported through it instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestAssertContains(t *testing.T) {
+	c := newTestCache(t)
+	cached := withResourceVersion(deployment("default", "web", map[string]string{"app": "web"}), "3")
+	mustAdd(t, c, cached)
+
+	tests := []struct {
+		name     string
+		expected client.Object
+		failures int
+	}{
+		{name: "equal but for the resource version", expected: deployment("default", "web", map[string]string{"app": "web"})},
+		{name: "different labels", expected: deployment("default", "web", map[string]string{"app": "db"}), failures: 1},
+		{name: "missing", expected: deployment("default", "db", nil), failures: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recordingTB{TB: t}
+			AssertContains(r, c, tt.expected)
+			if len(r.failures) != tt.failures {
+				t.Errorf("got failures %q, want %d", r.failures, tt.failures)
+			}
+		})
+	}
+}
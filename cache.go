@@ -1,16 +1,27 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/selection"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/tools/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
@@ -20,188 +31,1741 @@ var (
 	supportedKinds = []client.Object{}
 	ErrNilObj      = errors.New("object is nil")
 	ErrGvkNotFound = errors.New("gvk not found in the cache")
+	ErrNotFound    = errors.New("object not found in the cache")
+	// ErrStopEach stops Each without error when returned by its callback.
+	ErrStopEach = errors.New("stop iterating")
+	// ErrIndexExists is returned by IndexField when the field is already indexed.
+	ErrIndexExists = errors.New("index already exists")
 )
 
 type cacheStore map[schema.GroupVersionKind]cache.Indexer
 
+// CacheStores holds a cache.Indexer per GVK.
+//
+// CacheStores is safe for concurrent use. Reads (Get, List) share a read lock while
+// mutations (Add, Update, Delete) and lazy GVK registration take the write lock. The
+// lock guards the storesByGvk map; the stores themselves are thread-safe client-go indexers.
+// Registered event handlers are called after the lock is released.
 type CacheStores struct {
+	mu          sync.RWMutex
 	storesByGvk cacheStore
-	scheme      *runtime.Scheme
+	handlers    map[schema.GroupVersionKind][]cache.ResourceEventHandler
+	synced      map[schema.GroupVersionKind]chan struct{}
+	keyFuncs    map[schema.GroupVersionKind]cache.KeyFunc
+	observer    atomic.Pointer[observerHolder]
+	logger      atomic.Pointer[logr.Logger]
+	restMapper  apimeta.RESTMapper
+	generations map[schema.GroupVersionKind]uint64
+	indexSpecs  map[schema.GroupVersionKind]map[string]string
+	transform   cache.TransformFunc
+	transforms  map[schema.GroupVersionKind]cache.TransformFunc
+	limits      map[schema.GroupVersionKind]*sizeLimit
+	modified    map[schema.GroupVersionKind]map[string]time.Time
+	conversion  bool
+	composites  map[schema.GroupVersionKind][]compositeIndex
+	schemesMu   sync.RWMutex
+	schemes     []*runtime.Scheme
+	loadsMu     sync.Mutex
+	loader      client.Reader
+	loads       map[string]*loadCall
+
+	// resourceVersion is incremented by every mutation of the cache.
+	resourceVersion uint64
+	// namespacedOnly holds the field indexes registered with IndexNamespacedOnly.
+	namespacedOnly map[schema.GroupVersionKind]map[string]bool
+	// aliases holds the GVKs registered by AliasGVK.
+	aliases map[schema.GroupVersionKind]schema.GroupVersionKind
+
+	// statusSubresources holds the GVKs registered by EnableStatusSubresource, and
+	// statusVersions the resource versions of the statuses of their objects.
+	statusSubresources map[schema.GroupVersionKind]bool
+	statusVersions     map[schema.GroupVersionKind]map[string]uint64
+
+	dispatchMu    sync.Mutex
+	asyncDispatch bool
+	dispatchers   []*dispatcher
+
+	// reapers holds the goroutines started by StartTTLReaper.
+	reapersMu sync.Mutex
+	reapers   []*reaper
+
+	// indexPrefix prefixes the names of the field indexes, see WithIndexPrefix.
+	indexPrefix string
+}
+
+// New returns a CacheStores resolving the GVKs of objects through the given scheme, and the
+// ones added by AddScheme, with a store registered for every kind of supportedKinds and
+// configured by the given options. It returns an error if the scheme is nil, if the field
+// index prefix is empty or the one of label or composite indexes, or if one of the
+// supported kinds is not registered in it.
+func New(scheme *runtime.Scheme, opts ...Option) (*CacheStores, error) {
+	if scheme == nil {
+		return nil, errors.New("cannot create the cache with a nil scheme")
+	}
+
+	s := &CacheStores{
+		storesByGvk: make(cacheStore),
+		schemes:     []*runtime.Scheme{scheme},
+		indexPrefix: FieldIndexPrefix,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.indexPrefix == "" {
+		return nil, errors.New("cannot create the cache with an empty field index prefix")
+	}
+	if s.indexPrefix == labelIdxPrefix || s.indexPrefix == compositeIdxPrefix {
+		return nil, fmt.Errorf("cannot create the cache with the field index prefix %q of the label or composite indexes", s.indexPrefix)
+	}
+
+	for i := range supportedKinds {
+		gvk, err := gvkFromObject(supportedKinds[i], scheme)
+		if err != nil {
+			return nil, fmt.Errorf("supported kind %T is not registered in the scheme: %w", supportedKinds[i], err)
+		}
+
+		s.registerGvkIntoCache(*gvk, cache.MetaNamespaceKeyFunc)
+	}
+
+	return s, nil
+}
+
+// RegisterKind registers a store for the GVK of the given object unless it already exists.
+// It returns an error if the GVK can not be resolved from the scheme.
+func (s *CacheStores) RegisterKind(obj client.Object) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.storesByGvk[*gvk] == nil {
+		s.registerGvkIntoCache(*gvk, cache.MetaNamespaceKeyFunc)
+	}
+
+	return nil
+}
+
+// UnregisterKind removes the store of the GVK of the given object, with its objects and
+// indexes, e.g. once a dynamic controller stops watching the kind. The event handlers of
+// the GVK are notified through OnDelete about each removed object, then unregistered, and
+// their goroutines stopped once the events are delivered if they are dispatched
+// asynchronously. The synced mark of the GVK is reset, while the WaitForSync calls still
+// waiting for it keep waiting until it is marked again. The transforms, the cap set by
+// SetMaxSize and the status subresource settings of the GVK are kept, and apply if it is
+// registered again. It returns ErrGvkNotFound if the GVK is not registered.
+func (s *CacheStores) UnregisterKind(obj client.Object) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		s.mu.Unlock()
+		return ErrGvkNotFound
+	}
+
+	removed := store.List()
+	registered := s.handlers[*gvk]
+	handlers := s.reserveHandlers(*gvk)
+	defer releaseHandlers(handlers)
+	delete(s.storesByGvk, *gvk)
+	delete(s.handlers, *gvk)
+	// a channel not closed yet may be waited for, and is closed by the next MarkSynced.
+	if ch := s.synced[*gvk]; ch != nil {
+		select {
+		case <-ch:
+			delete(s.synced, *gvk)
+		default:
+		}
+	}
+	delete(s.keyFuncs, *gvk)
+	delete(s.indexSpecs, *gvk)
+	delete(s.namespacedOnly, *gvk)
+	delete(s.composites, *gvk)
+	s.trackCleared(*gvk)
+	// the generation is kept so that it keeps increasing if the GVK is registered again.
+	s.bumpGeneration(*gvk)
+	s.mu.Unlock()
+
+	s.log().V(1).Info("unregistered kind", "gvk", gvk.String(), "objects", len(removed))
+	// handlers are notified in the order AddEventHandler replays the objects. Sorting can
+	// not fail, as the cached objects all have metadata.
+	_ = sortByName(removed)
+	observer := s.observe()
+	for _, obj := range removed {
+		observer.OnDelete(*gvk)
+		s.notifyDelete(handlers, obj)
+	}
+	s.stopDispatchers(registered)
+
+	return nil
+}
+
+// RegisterKindWithKeyFunc registers a store for the GVK of the given object whose objects
+// are keyed by keyFunc instead of cache.MetaNamespaceKeyFunc. Get, Has, Update and Delete
+// compute keys with the same function, whereas Reader, GetInto, TypedCache and DeleteByKey
+// look objects up by namespace and name through the metadata.name index, and return an
+// error if several objects share them. It returns an error if the GVK is already
+// registered, as the key function of an existing store can not be changed.
+func (s *CacheStores) RegisterKindWithKeyFunc(obj client.Object, keyFunc cache.KeyFunc) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+	if keyFunc == nil {
+		return fmt.Errorf("cannot register kind with nil key function")
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.storesByGvk[*gvk] != nil {
+		return fmt.Errorf("%s is already registered", gvk)
+	}
+
+	s.registerGvkIntoCache(*gvk, keyFunc)
+	if s.keyFuncs == nil {
+		s.keyFuncs = make(map[schema.GroupVersionKind]cache.KeyFunc)
+	}
+	s.keyFuncs[*gvk] = keyFunc
+
+	return nil
+}
+
+// keyFunc returns the key function of the store of the given GVK. It must be called with
+// the lock held.
+func (s *CacheStores) keyFunc(gvk schema.GroupVersionKind) cache.KeyFunc {
+	if keyFunc := s.keyFuncs[gvk]; keyFunc != nil {
+		return keyFunc
+	}
+	return cache.MetaNamespaceKeyFunc
+}
+
+// List works with structured types, unstructured.UnstructuredList and
+// metav1.PartialObjectMetadataList; the items are converted to the representation of the
+// given list, whatever the representation they were added in. The items are sorted by
+// namespace, then by name, or by key for the kinds registered with RegisterKindWithKeyFunc,
+// unless the Unsorted option is given.
+//
+// If the Limit option leaves matching objects out, the Continue field of the list is set to
+// a token which, passed through client.Continue, lists the objects following this page.
+// The ResourceVersion field of the list is set to the resource version of the cache, which
+// is incremented by every mutation, at the time of the listing.
+func (s *CacheStores) List(out client.ObjectList, opts ...client.ListOption) error {
+	return s.ListCtx(context.Background(), out, opts...)
+}
+
+// ListWithTotal is like List but also returns the number of objects matching the list
+// options regardless of the Limit and Continue options, i.e. the total across all pages.
+func (s *CacheStores) ListWithTotal(out client.ObjectList, opts ...client.ListOption) (int, error) {
+	var total int
+	opts = append(opts[:len(opts):len(opts)], countTotal{total: &total})
+	if err := s.List(out, opts...); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// ListCtx is like List but stops with the error of ctx once it is done. The context is
+// checked before listing and while filtering the listed objects.
+func (s *CacheStores) ListCtx(ctx context.Context, out client.ObjectList, opts ...client.ListOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if out == nil {
+		return ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(out)
+	if err != nil {
+		return err
+	}
+
+	kind, isList := strings.CutSuffix(gvk.Kind, "List")
+	if !isList {
+		return fmt.Errorf("List called with non-list type %T", out)
+	}
+	gvk.Kind = kind
+	*gvk = s.resolveAlias(*gvk)
+
+	listOpts := listOptions{}
+	listOpts.applyOptions(opts)
+
+	var resourceVersion string
+	opts = append(opts[:len(opts):len(opts)], snapshotVersion{resourceVersion: &resourceVersion})
+	runtimeObjs, continueToken, err := s.list(ctx, *gvk, true, opts...)
+	if err != nil {
+		return err
+	}
+
+	if listOpts.projection != nil {
+		for _, obj := range runtimeObjs {
+			clientObj, ok := obj.(client.Object)
+			if !ok {
+				return fmt.Errorf("cache contained %T, which is not a client.Object", obj)
+			}
+			listOpts.projection(clientObj)
+		}
+		runtimeObjs = nil
+	} else {
+		runtimeObjs, err = convertObjects(runtimeObjs, representationOf(out), s.schemeFor(*gvk), *gvk)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = apimeta.SetList(out, runtimeObjs)
+	if err != nil {
+		return err
+	}
+
+	out.SetContinue(continueToken)
+	out.SetResourceVersion(resourceVersion)
+
+	return nil
+}
+
+// Each calls fn with a copy of every object sharing the GVK of the given object which
+// matches the list options, in the order of List and in the representation of the given
+// object. Unlike List, the matching objects are copied one at a time, so only the object
+// being visited is materialized. Iteration stops at the first error returned by fn, which
+// Each returns unless it is ErrStopEach. The cache is not locked while fn runs.
+func (s *CacheStores) Each(obj client.Object, fn func(client.Object) error, opts ...client.ListOption) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+	if fn == nil {
+		return fmt.Errorf("cannot iterate with a nil function")
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+	*gvk = s.resolveAlias(*gvk)
+
+	runtimeObjs, _, err := s.list(context.Background(), *gvk, false, opts...)
+	if err != nil {
+		return err
+	}
+
+	to := representationOf(obj)
+	for _, runtimeObj := range runtimeObjs {
+		converted, err := convertObjects([]runtime.Object{runtimeObj.DeepCopyObject()}, to, s.schemeFor(*gvk), *gvk)
+		if err != nil {
+			return err
+		}
+		clientObj, ok := converted[0].(client.Object)
+		if !ok {
+			return fmt.Errorf("cache contained %T, which is not a client.Object", converted[0])
+		}
+		clientObj.GetObjectKind().SetGroupVersionKind(*gvk)
+
+		if err := fn(clientObj); err != nil {
+			if errors.Is(err, ErrStopEach) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListRefs returns the objects of the given GVK matching the list options without copying
+// them. The returned objects are the ones held by the cache: they must not be modified, and
+// they are only meant for read-only callers which can not afford the copies made by List.
+func (s *CacheStores) ListRefs(gvk schema.GroupVersionKind, opts ...client.ListOption) ([]client.Object, error) {
+	gvk = s.resolveAlias(gvk)
+	runtimeObjs, _, err := s.list(context.Background(), gvk, false, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := make([]client.Object, 0, len(runtimeObjs))
+	for _, runtimeObj := range runtimeObjs {
+		obj, ok := runtimeObj.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("cache contained %T, which is not a client.Object", runtimeObj)
+		}
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
+
+// list returns the objects of the given GVK matching the list options, deep copied unless
+// copyObjects is false, and the continue token of the next page if the limit left some of
+// them out.
+func (s *CacheStores) list(ctx context.Context, gvk schema.GroupVersionKind, copyObjects bool, opts ...client.ListOption) ([]runtime.Object, string, error) {
+	start := time.Now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	store, storedGvk := s.storeFor(gvk)
+	if store == nil {
+		return nil, "", s.gvkNotFound(gvk)
+	}
+
+	listOpts := listOptions{}
+	listOpts.applyOptions(opts)
+	if listOpts.resourceVersion != nil {
+		*listOpts.resourceVersion = strconv.FormatUint(s.resourceVersion, 10)
+	}
+
+	if err := s.validateNamespace(gvk, listOpts.Namespace); err != nil {
+		return nil, "", err
+	}
+
+	var (
+		objs []interface{}
+		err  error
+	)
+
+	labelIdx, labelVal, labelIndexed := labelIndexRequirement(store, listOpts.LabelSelector)
+
+	switch {
+	// an empty field selector, e.g. fields.Everything(), matches every object.
+	case listOpts.FieldSelector != nil && !listOpts.FieldSelector.Empty():
+		requiresExact := requiresExactMatch(listOpts.FieldSelector)
+		if !requiresExact {
+			return nil, "", fmt.Errorf("non-exact field matches are not supported by the cache")
+		}
+		// list all objects by the field selector. If this is namespaced and we have one, ask for the
+		// namespaced index key. Otherwise, ask for the non-namespaced variant by using the fake "all namespaces"
+		// namespace.
+		reqs := listOpts.FieldSelector.Requirements()
+		if listOpts.Namespace == "" {
+			for _, req := range reqs {
+				if s.namespacedOnly[storedGvk][s.fieldIdxName(req.Field)] {
+					return nil, "", fmt.Errorf("field index %s is namespaced only, list in a namespace to use it", req.Field)
+				}
+			}
+		}
+		if idx, key, ok := s.compositeIndexRequirement(storedGvk, reqs); ok {
+			objs, err = store.ByIndex(idx, keyToNamespacedKey(listOpts.Namespace, key))
+		} else {
+			objs, err = s.byIndexes(store, reqs, listOpts.Namespace)
+		}
+		// both selectors are answered by the indexes when the label is indexed as well.
+		if err == nil && labelIndexed && len(objs) > 0 {
+			var labelObjs []interface{}
+			labelObjs, err = store.ByIndex(labelIdx, keyToNamespacedKey(listOpts.Namespace, labelVal))
+			objs = intersectObjects(objs, labelObjs)
+		}
+	case labelIndexed:
+		objs, err = store.ByIndex(labelIdx, keyToNamespacedKey(listOpts.Namespace, labelVal))
+	case listOpts.Namespace != "":
+		objs, err = store.ByIndex(cache.NamespaceIndex, listOpts.Namespace)
+	default:
+		objs = store.List()
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	var labelSel labels.Selector
+	if listOpts.LabelSelector != nil && !labelAbsentFromIndex(store, listOpts.LabelSelector, listOpts.Namespace) {
+		labelSel = listOpts.LabelSelector
+	}
+
+	limitSet := listOpts.Limit > 0
+
+	customKeyFunc := s.keyFuncs[storedGvk]
+	keyOf := pageKeyFunc(customKeyFunc)
+	var continueAfter *pageKey
+	if listOpts.Continue != "" {
+		key, err := decodeContinueToken(listOpts.Continue, customKeyFunc != nil)
+		if err != nil {
+			return nil, "", err
+		}
+		continueAfter = &key
+	}
+
+	if !listOpts.unsorted || limitSet || continueAfter != nil {
+		// the indexer returns objects in a random order, sort them so that lists are
+		// reproducible, the limit always returns the same prefix and pages can be resumed.
+		if err := sortByPageKey(objs, keyOf); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var (
+		continueToken string
+		lastKey       pageKey
+	)
+	runtimeObjs := make([]runtime.Object, 0, len(objs))
+	for _, item := range objs {
+		if err := ctx.Err(); err != nil {
+			return nil, "", err
+		}
+		obj, isObj := item.(runtime.Object)
+		if !isObj {
+			return nil, "", fmt.Errorf("cache contained %T, which is not an Object", item)
+		}
+		meta, err := apimeta.Accessor(obj)
+		if err != nil {
+			return nil, "", err
+		}
+		if listOpts.excludeTerminating && meta.GetDeletionTimestamp() != nil {
+			continue
+		}
+		if labelSel != nil {
+			lbls := labels.Set(meta.GetLabels())
+			if !labelSel.Matches(lbls) {
+				continue
+			}
+		}
+		matches, err := s.matchesFilters(listOpts.filters, obj)
+		if err != nil {
+			return nil, "", err
+		}
+		if !matches {
+			continue
+		}
+		if listOpts.total != nil {
+			*listOpts.total++
+		}
+		var key pageKey
+		if limitSet || continueAfter != nil {
+			if key, err = keyOf(item); err != nil {
+				return nil, "", err
+			}
+		}
+		if continueAfter != nil && comparePageKeys(key, *continueAfter) <= 0 {
+			continue
+		}
+
+		// if the Limit option is set and the number of items listed reaches
+		// this limit, then stop reading and let the caller continue from the last item.
+		// The remaining objects are still filtered when their total is requested.
+		if limitSet && int64(len(runtimeObjs)) >= listOpts.Limit {
+			if continueToken == "" {
+				continueToken = encodeContinueToken(lastKey.String())
+			}
+			if listOpts.total == nil {
+				break
+			}
+			continue
+		}
+
+		lastKey = key
+		if storedGvk != gvk {
+			converted, err := s.convertVersion(obj, gvk)
+			if err != nil {
+				return nil, "", err
+			}
+			runtimeObjs = append(runtimeObjs, converted)
+			continue
+		}
+		if !copyObjects {
+			runtimeObjs = append(runtimeObjs, obj)
+			continue
+		}
+
+		var outObj runtime.Object
+		outObj = obj.DeepCopyObject()
+		outObj.GetObjectKind().SetGroupVersionKind(gvk)
+		runtimeObjs = append(runtimeObjs, outObj)
+	}
+
+	s.observe().OnList(gvk, time.Since(start), len(runtimeObjs))
+
+	return runtimeObjs, continueToken, nil
+}
+
+// GetCtx is like Get but returns the error of ctx if it is already done.
+func (s *CacheStores) GetCtx(ctx context.Context, obj client.Object) (item interface{}, exists bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	return s.Get(obj)
+}
+
+// Get returns a copy of the cached object sharing the key of the given object, converted
+// to the representation of the given object. It returns ErrGvkNotFound if the GVK of the
+// object is not registered, unless a loader is set, see SetLoader, from which missing
+// objects are loaded.
+//
+// Callers relying on apierrors.IsNotFound, e.g. controllers, should use GetInto, which
+// reports missing objects and unregistered GVKs alike as NotFound API errors.
+func (s *CacheStores) Get(obj client.Object) (item interface{}, exists bool, err error) {
+	if obj == nil {
+		return nil, false, fmt.Errorf("cannot add nil object")
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return nil, false, err
+	}
+	*gvk = s.resolveAlias(*gvk)
+
+	s.mu.RLock()
+	store, _ := s.storeFor(*gvk)
+	key, err := s.keyFunc(*gvk)(obj)
+	s.mu.RUnlock()
+	if store == nil && !s.hasLoader() {
+		return nil, false, ErrGvkNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	item, exists, err = s.getOrLoad(*gvk, client.ObjectKeyFromObject(obj), func() (interface{}, bool, error) {
+		return s.GetByKey(*gvk, key)
+	})
+	if err != nil || !exists {
+		return item, exists, err
+	}
+
+	converted, err := convertObjects([]runtime.Object{item.(runtime.Object)}, representationOf(obj), s.schemeFor(*gvk), *gvk)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return converted[0], true, nil
+}
+
+// Has reports whether the given object is cached. It returns false for unregistered GVKs.
+func (s *CacheStores) Has(obj client.Object) (bool, error) {
+	if obj == nil {
+		return false, ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		return false, nil
+	}
+
+	_, exists, err := store.Get(obj)
+	return exists, err
+}
+
+// GetByKey returns a copy of the object stored under the given key in the store of the
+// given GVK, e.g. the key of a cache.DeletedFinalStateUnknown. Keys are computed by the key
+// function of the store, namespace/name by default.
+func (s *CacheStores) GetByKey(gvk schema.GroupVersionKind, key string) (item interface{}, exists bool, err error) {
+	gvk = s.resolveAlias(gvk)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	store, storedGvk := s.storeFor(gvk)
+	if store == nil {
+		return nil, false, nil
+	}
+
+	item, exists, err = store.GetByKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	s.observe().OnGet(gvk, exists)
+	if !exists {
+		return nil, false, nil
+	}
+
+	obj, isObj := item.(runtime.Object)
+	if !isObj {
+		return nil, false, fmt.Errorf("cache contained %T, which is not an Object", item)
+	}
+	if err := s.checkCachedKind(obj, storedGvk); err != nil {
+		return nil, false, err
+	}
+
+	if storedGvk != gvk {
+		converted, err := s.convertVersion(obj, gvk)
+		if err != nil {
+			return nil, false, err
+		}
+		return converted, true, nil
+	}
+
+	// return a copy, like List does, so that callers can not corrupt the cached object.
+	obj = obj.DeepCopyObject()
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+
+	return obj, true, nil
+}
+
+// checkCachedKind returns an error if the given cached object is not of the GVK of its
+// store, which can only happen if the store was modified through the indexer returned by
+// GetByType.
+func (s *CacheStores) checkCachedKind(obj runtime.Object, gvk schema.GroupVersionKind) error {
+	objGvk := obj.GetObjectKind().GroupVersionKind()
+	if objGvk.Empty() {
+		resolved, err := s.gvkFor(obj)
+		if err != nil {
+			return fmt.Errorf("cache contained %T in the store of %s, whose GVK can not be resolved: %w", obj, gvk, err)
+		}
+		objGvk = *resolved
+	}
+	if objGvk != gvk {
+		return fmt.Errorf("cache contained a %s object of type %T in the store of %s", objGvk, obj, gvk)
+	}
+
+	return nil
+}
+
+// GetInto populates out with a copy of the object stored under the given key in the store
+// of the GVK of out, converted to the representation of out. It returns a NotFound API
+// error if the object is not cached, nor found by the loader set by SetLoader. The resource
+// of the error is mapped by the RESTMapper set by SetRESTMapper, or guessed from the kind.
+func (s *CacheStores) GetInto(key client.ObjectKey, out client.Object) error {
+	if out == nil {
+		return ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(out)
+	if err != nil {
+		return err
+	}
+	*gvk = s.resolveAlias(*gvk)
+
+	item, exists, err := s.getOrLoad(*gvk, key, func() (interface{}, bool, error) {
+		return s.getByObjectKey(*gvk, key)
+	})
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apierrors.NewNotFound(s.resourceFor(*gvk), key.Name)
+	}
+
+	converted, err := convertObjects([]runtime.Object{item.(runtime.Object)}, representationOf(out), s.schemeFor(*gvk), *gvk)
+	if err != nil {
+		return err
+	}
+
+	return copyInto(converted[0], out, *gvk)
+}
+
+// Delete deletes the given object from the cache. Deleting an object which is not cached
+// is a no-op, see DeleteIfExists to tell both cases apart. It returns ErrGvkNotFound if the
+// GVK of the object is not registered.
+func (s *CacheStores) Delete(obj client.Object) error {
+	_, err := s.DeleteIfExists(obj)
+	return err
+}
+
+// DeleteIfExists deletes the given object from the cache and reports whether it was cached.
+// Event handlers are only notified when an object was actually removed.
+func (s *CacheStores) DeleteIfExists(obj client.Object) (bool, error) {
+	if obj == nil {
+		return false, fmt.Errorf("cannot delete nil object")
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		s.mu.Unlock()
+		return false, ErrGvkNotFound
+	}
+
+	old, exists, err := store.Get(obj)
+	if err == nil && exists {
+		err = store.Delete(obj)
+	}
+	if err == nil && exists {
+		s.trackDeleted(*gvk, old)
+		s.bumpGeneration(*gvk)
+	}
+	handlers := s.reserveHandlers(*gvk)
+	defer releaseHandlers(handlers)
+	s.mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+
+	if exists {
+		s.observe().OnDelete(*gvk)
+		s.notifyDelete(handlers, old)
+	}
+
+	return exists, nil
+}
+
+// DeleteAllOf deletes the objects sharing the GVK of the given object which match the list
+// options, as selected by List. Event handlers are notified about each deleted object.
+// Failed deletions do not stop the others; their errors are aggregated.
+func (s *CacheStores) DeleteAllOf(obj client.Object, opts ...client.ListOption) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	matches, _, err := s.list(context.Background(), *gvk, false, opts...)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, match := range matches {
+		matchObj, ok := match.(client.Object)
+		if !ok {
+			errs = append(errs, fmt.Errorf("cache contained %T, which is not a client.Object", match))
+			continue
+		}
+		if _, err := s.DeleteIfExists(matchObj); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %s %s: %w", gvk.Kind, client.ObjectKeyFromObject(matchObj), err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// DeleteByKey deletes the object stored under the given key from the store of the given GVK.
+// It returns ErrGvkNotFound if the GVK is not registered and nil if the key is not present.
+func (s *CacheStores) DeleteByKey(gvk schema.GroupVersionKind, key client.ObjectKey) error {
+	s.mu.Lock()
+	store := s.storesByGvk[gvk]
+	if store == nil {
+		s.mu.Unlock()
+		return ErrGvkNotFound
+	}
+
+	var (
+		old    interface{}
+		exists bool
+	)
+	storeKey, found, err := s.objectStoreKey(gvk, store, key)
+	if err == nil && found {
+		old, exists, err = store.GetByKey(storeKey)
+	}
+	if err == nil && exists {
+		err = store.Delete(old)
+	}
+	if err == nil && exists {
+		s.trackDeleted(gvk, old)
+		s.bumpGeneration(gvk)
+	}
+	handlers := s.reserveHandlers(gvk)
+	defer releaseHandlers(handlers)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		s.observe().OnDelete(gvk)
+		s.notifyDelete(handlers, old)
+	}
+
+	return nil
+}
+
+// AddCtx is like Add but returns the error of ctx if it is already done.
+func (s *CacheStores) AddCtx(ctx context.Context, obj client.Object) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.Add(obj)
+}
+
+// Add adds the given object to the cache, replacing the cached object sharing its key. The
+// resource version of a replacing object is bumped past the cached one unless it is
+// already greater, see Update.
+func (s *CacheStores) Add(obj client.Object) error {
+	_, _, err := s.Upsert(obj)
+	return err
+}
+
+// Upsert is like Add but also returns a copy of the object it replaced, if any.
+func (s *CacheStores) Upsert(obj client.Object) (previous client.Object, existed bool, err error) {
+	if obj == nil {
+		return nil, false, fmt.Errorf("cannot add nil object")
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return nil, false, err
+	}
+
+	stored := withGVK(obj, *gvk)
+
+	s.mu.Lock()
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		store = s.registerGvkIntoCache(*gvk, cache.MetaNamespaceKeyFunc)
+	}
+
+	stored, err = s.transformed(*gvk, stored)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, false, err
+	}
+
+	var evicted []interface{}
+	old, exists, err := store.Get(stored)
+	if err == nil && exists {
+		err = overwriteResourceVersion(old, stored)
+	}
+	if err == nil {
+		err = s.checkIndexable(store.GetIndexers(), stored)
+	}
+	if err == nil {
+		err = store.Add(stored)
+	}
+	if err == nil {
+		s.trackModified(*gvk, stored)
+		s.bumpGeneration(*gvk)
+	}
+	if err == nil && !exists {
+		evicted, err = s.trackAdded(*gvk, store, stored)
+	}
+	handlers := s.reserveHandlers(*gvk)
+	defer releaseHandlers(handlers)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, false, err
+	}
+
+	s.observe().OnAdd(*gvk)
+	if exists {
+		s.notifyUpdate(handlers, old, stored)
+	} else {
+		s.notifyAdd(handlers, stored)
+	}
+	s.notifyEvicted(*gvk, handlers, evicted)
+
+	if !exists {
+		return nil, false, nil
+	}
+	oldObj, ok := old.(client.Object)
+	if !ok {
+		return nil, true, fmt.Errorf("cache contained %T, which is not a client.Object", old)
+	}
+
+	return withGVK(oldObj, *gvk), true, nil
+}
+
+// GetOrCreate returns a copy of the cached object sharing the key of the given object or,
+// if there is none, adds the given object and returns a copy of it with created set.
+// The lookup and the insertion happen under a single lock acquisition.
+func (s *CacheStores) GetOrCreate(obj client.Object) (result client.Object, created bool, err error) {
+	if obj == nil {
+		return nil, false, ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return nil, false, err
+	}
+
+	stored := withGVK(obj, *gvk)
+
+	s.mu.Lock()
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		store = s.registerGvkIntoCache(*gvk, cache.MetaNamespaceKeyFunc)
+	}
+
+	var evicted []interface{}
+	existing, exists, err := store.Get(stored)
+	if err == nil && !exists {
+		stored, err = s.transformed(*gvk, stored)
+		if err == nil {
+			err = s.checkIndexable(store.GetIndexers(), stored)
+		}
+		if err == nil {
+			err = store.Add(stored)
+		}
+		if err == nil {
+			s.trackModified(*gvk, stored)
+			s.bumpGeneration(*gvk)
+			evicted, err = s.trackAdded(*gvk, store, stored)
+		}
+	}
+	handlers := s.reserveHandlers(*gvk)
+	defer releaseHandlers(handlers)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if exists {
+		existingObj, ok := existing.(client.Object)
+		if !ok {
+			return nil, false, fmt.Errorf("cache contained %T, which is not a client.Object", existing)
+		}
+		return withGVK(existingObj, *gvk), false, nil
+	}
+
+	s.observe().OnAdd(*gvk)
+	s.notifyAdd(handlers, stored)
+	s.notifyEvicted(*gvk, handlers, evicted)
+
+	return withGVK(stored, *gvk), true, nil
+}
+
+// AddAll adds the given objects to the cache, e.g. to seed it from a list response. The GVK
+// of each Go type is resolved once and the objects are added under a single lock acquisition.
+// Objects failing to be added do not stop the others; their errors are aggregated. The
+// resource versions of the objects replacing cached ones are bumped as by Add.
+func (s *CacheStores) AddAll(objs ...client.Object) error {
+	var (
+		errs    []error
+		gvks    []schema.GroupVersionKind
+		byGvk   = make(map[schema.GroupVersionKind][]client.Object)
+		byType  = make(map[reflect.Type]schema.GroupVersionKind)
+		pending []addEvent
+		evicted = make(map[schema.GroupVersionKind][]interface{})
+		// reserved holds the handlers of each GVK, see reserveHandlers.
+		reserved = make(map[schema.GroupVersionKind][]cache.ResourceEventHandler)
+	)
+
+	for _, obj := range objs {
+		if obj == nil {
+			errs = append(errs, ErrNilObj)
+			continue
+		}
+
+		// unstructured and partial objects share a Go type, their GVK is resolved one by one.
+		t := reflect.TypeOf(obj)
+		gvk, cached := byType[t]
+		if !cached {
+			resolved, err := s.gvkFor(obj)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			gvk = *resolved
+			if representationOf(obj) == structured {
+				byType[t] = gvk
+			}
+		}
+
+		if _, exists := byGvk[gvk]; !exists {
+			gvks = append(gvks, gvk)
+		}
+		byGvk[gvk] = append(byGvk[gvk], withGVK(obj, gvk))
+	}
+
+	s.mu.Lock()
+	for _, gvk := range gvks {
+		store := s.storesByGvk[gvk]
+		if store == nil {
+			store = s.registerGvkIntoCache(gvk, cache.MetaNamespaceKeyFunc)
+		}
+		handlers := s.reserveHandlers(gvk)
+		defer releaseHandlers(handlers)
+		reserved[gvk] = handlers
+
+		for _, obj := range byGvk[gvk] {
+			var (
+				old    interface{}
+				exists bool
+			)
+			stored, err := s.transformed(gvk, obj)
+			if err == nil {
+				old, exists, err = store.Get(stored)
+			}
+			if err == nil && exists {
+				err = overwriteResourceVersion(old, stored)
+			}
+			if err == nil {
+				err = s.checkIndexable(store.GetIndexers(), stored)
+			}
+			if err == nil {
+				err = store.Add(stored)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to add %s %s: %w", gvk.Kind, client.ObjectKeyFromObject(obj), err))
+				continue
+			}
+			s.trackModified(gvk, stored)
+			s.bumpGeneration(gvk)
+			pending = append(pending, addEvent{gvk: gvk, handlers: handlers, old: old, obj: stored, exists: exists})
+
+			if !exists {
+				objEvicted, err := s.trackAdded(gvk, store, stored)
+				evicted[gvk] = append(evicted[gvk], objEvicted...)
+				if err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	observer := s.observe()
+	for _, e := range pending {
+		observer.OnAdd(e.gvk)
+		if e.exists {
+			s.notifyUpdate(e.handlers, e.old, e.obj)
+		} else {
+			s.notifyAdd(e.handlers, e.obj)
+		}
+	}
+	for gvk, objs := range evicted {
+		s.notifyEvicted(gvk, reserved[gvk], objs)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// Replace atomically replaces the objects of the given GVK with the given ones, e.g. after a
+// relist. Every object must map to the given GVK. Event handlers are notified through OnDelete
+// about the objects which are no longer present, and through OnAdd or OnUpdate about the others.
+// The resource versions of the objects replacing cached ones are bumped as by Add. Of several
+// objects sharing a key, the last one is stored, with a single event.
+func (s *CacheStores) Replace(gvk schema.GroupVersionKind, objs []client.Object, resourceVersion string) error {
+	items := make([]interface{}, 0, len(objs))
+	for _, obj := range objs {
+		if obj == nil {
+			return ErrNilObj
+		}
+
+		objGvk, err := s.gvkFor(obj)
+		if err != nil {
+			return err
+		}
+		if *objGvk != gvk {
+			return fmt.Errorf("cannot replace %s objects with %s %s", gvk, objGvk, client.ObjectKeyFromObject(obj))
+		}
+
+		items = append(items, withGVK(obj, gvk))
+	}
+
+	s.mu.Lock()
+	store := s.storesByGvk[gvk]
+	if store == nil {
+		store = s.registerGvkIntoCache(gvk, cache.MetaNamespaceKeyFunc)
+	}
+
+	keyFunc := s.keyFunc(gvk)
+	oldObjs := make(map[string]interface{})
+	for _, old := range store.List() {
+		key, err := keyFunc(old)
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		oldObjs[key] = old
+	}
+
+	pending := make([]addEvent, 0, len(items))
+	// pendingByKey holds the index of the event of each key in pending, so that the last of
+	// the objects sharing a key replaces the others, as in the store.
+	pendingByKey := make(map[string]int, len(items))
+	for i, item := range items {
+		item, err := s.transformed(gvk, item.(client.Object))
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		items[i] = item
+
+		key, err := keyFunc(item)
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		old, exists := oldObjs[key]
+		if idx, seen := pendingByKey[key]; seen {
+			old, exists = pending[idx].old, pending[idx].exists
+		}
+		if exists {
+			if err := overwriteResourceVersion(old, item.(client.Object)); err != nil {
+				s.mu.Unlock()
+				return err
+			}
+		}
+		delete(oldObjs, key)
+		if idx, seen := pendingByKey[key]; seen {
+			pending[idx].obj = item
+			continue
+		}
+		pendingByKey[key] = len(pending)
+		pending = append(pending, addEvent{old: old, obj: item, exists: exists})
+	}
+
+	if err := s.checkIndexable(store.GetIndexers(), items...); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	if err := store.Replace(items, resourceVersion); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.bumpGeneration(gvk)
+
+	var (
+		evicted  []interface{}
+		trackErr error
+	)
+	for _, old := range oldObjs {
+		s.trackDeleted(gvk, old)
+	}
+	for _, e := range pending {
+		s.trackModified(gvk, e.obj)
+		if e.exists || trackErr != nil {
+			continue
+		}
+		var objEvicted []interface{}
+		objEvicted, trackErr = s.trackAdded(gvk, store, e.obj)
+		evicted = append(evicted, objEvicted...)
+	}
+	handlers := s.reserveHandlers(gvk)
+	defer releaseHandlers(handlers)
+	s.mu.Unlock()
+
+	observer := s.observe()
+	for _, old := range oldObjs {
+		observer.OnDelete(gvk)
+		s.notifyDelete(handlers, old)
+	}
+	for _, e := range pending {
+		observer.OnAdd(gvk)
+		if e.exists {
+			s.notifyUpdate(handlers, e.old, e.obj)
+		} else {
+			s.notifyAdd(handlers, e.obj)
+		}
+	}
+	s.notifyEvicted(gvk, handlers, evicted)
+
+	return trackErr
+}
+
+// Update replaces an object that already exists in the cache. It returns ErrNotFound
+// if the object's key is not present in the store of its GVK.
+//
+// Like the apiserver, Update returns a Conflict API error if the object has a resource
+// version that differs from the cached one, and increments the resource version of the
+// stored object on success. An object without a resource version is updated unconditionally.
+// The status of the objects of a GVK with a status subresource is left unchanged, see
+// EnableStatusSubresource.
+func (s *CacheStores) Update(obj client.Object) error {
+	if obj == nil {
+		return fmt.Errorf("cannot update nil object")
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	stored := withGVK(obj, *gvk)
+
+	s.mu.Lock()
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		s.mu.Unlock()
+		return ErrNotFound
+	}
+
+	stored, err = s.transformed(*gvk, stored)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	old, exists, err := store.Get(stored)
+	if err == nil && !exists {
+		err = ErrNotFound
+	}
+	if err == nil {
+		err = nextResourceVersion(*gvk, old, stored)
+	}
+	if err == nil {
+		stored, err = s.keepStatus(*gvk, old, stored)
+	}
+	if err == nil {
+		err = s.checkIndexable(store.GetIndexers(), stored)
+	}
+	if err == nil {
+		err = store.Update(stored)
+	}
+	if err == nil {
+		s.trackModified(*gvk, stored)
+		s.bumpGeneration(*gvk)
+	}
+	handlers := s.reserveHandlers(*gvk)
+	defer releaseHandlers(handlers)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	s.observe().OnAdd(*gvk)
+	s.notifyUpdate(handlers, old, stored)
+
+	return nil
+}
+
+// Clear removes every object of the GVK of the given object while keeping its registered
+// indexes. Event handlers are not notified about the removed objects.
+func (s *CacheStores) Clear(obj client.Object) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		return ErrGvkNotFound
+	}
+
+	if err := store.Replace(nil, ""); err != nil {
+		return err
+	}
+	s.trackCleared(*gvk)
+	s.bumpGeneration(*gvk)
+
+	return nil
+}
+
+// ClearAll removes every object from the cache while keeping the registered GVKs and indexes.
+func (s *CacheStores) ClearAll() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for gvk, store := range s.storesByGvk {
+		if err := store.Replace(nil, ""); err != nil {
+			return err
+		}
+		s.trackCleared(gvk)
+		s.bumpGeneration(gvk)
+	}
+
+	return nil
+}
+
+// withGVK returns a copy of the given object with its GVK set, so that the caller's object
+// is left untouched while Get and List return fully populated objects.
+func withGVK(obj client.Object, gvk schema.GroupVersionKind) client.Object {
+	stored := obj.DeepCopyObject().(client.Object)
+	stored.GetObjectKind().SetGroupVersionKind(gvk)
+	return stored
+}
+
+// nextResourceVersion sets the resource version of obj to the one following the resource
+// version of the cached old object. It returns a Conflict API error if obj is stale.
+func nextResourceVersion(gvk schema.GroupVersionKind, old interface{}, obj client.Object) error {
+	oldMeta, err := apimeta.Accessor(old)
+	if err != nil {
+		return err
+	}
+
+	oldRV := oldMeta.GetResourceVersion()
+	if rv := obj.GetResourceVersion(); rv != "" && rv != oldRV {
+		return apierrors.NewConflict(
+			groupResource(gvk),
+			obj.GetName(),
+			fmt.Errorf("the object has been modified; please apply your changes to the latest version and try again"),
+		)
+	}
+
+	// resource versions which are not integers, e.g. empty ones, restart from 1.
+	version, err := strconv.ParseUint(oldRV, 10, 64)
+	if err != nil {
+		version = 0
+	}
+	obj.SetResourceVersion(strconv.FormatUint(version+1, 10))
+
+	return nil
+}
+
+// overwriteResourceVersion sets the resource version of obj, which replaces the cached old
+// object without a conflict check, to the one following the resource version of old unless
+// obj already has a greater one, e.g. from the apiserver, so that the resource versions of
+// cached objects never go back.
+func overwriteResourceVersion(old interface{}, obj client.Object) error {
+	oldMeta, err := apimeta.Accessor(old)
+	if err != nil {
+		return err
+	}
+
+	// resource versions which are not integers, e.g. empty ones, count as 0.
+	oldVersion, err := strconv.ParseUint(oldMeta.GetResourceVersion(), 10, 64)
+	if err != nil {
+		oldVersion = 0
+	}
+	if version, err := strconv.ParseUint(obj.GetResourceVersion(), 10, 64); err == nil && version > oldVersion {
+		return nil
+	}
+	obj.SetResourceVersion(strconv.FormatUint(oldVersion+1, 10))
+
+	return nil
+}
+
+// Count returns the number of objects cached for the GVK of the given object without
+// copying them. It returns ErrGvkNotFound if the GVK was never registered.
+func (s *CacheStores) Count(obj client.Object) (int, error) {
+	if obj == nil {
+		return 0, ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		return 0, ErrGvkNotFound
+	}
+
+	return len(store.ListKeys()), nil
+}
+
+// ListKeys returns the keys of the objects cached for the GVK of the given object without
+// copying the objects. It returns ErrGvkNotFound if the GVK was never registered.
+func (s *CacheStores) ListKeys(obj client.Object) ([]string, error) {
+	if obj == nil {
+		return nil, ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		return nil, ErrGvkNotFound
+	}
+
+	return store.ListKeys(), nil
+}
+
+// RegisteredGVKs returns the GVKs registered in the cache, sorted by group, version and kind.
+func (s *CacheStores) RegisteredGVKs() []schema.GroupVersionKind {
+	s.mu.RLock()
+	gvks := make([]schema.GroupVersionKind, 0, len(s.storesByGvk))
+	for gvk := range s.storesByGvk {
+		gvks = append(gvks, gvk)
+	}
+	s.mu.RUnlock()
+
+	sortGVKs(gvks)
+
+	return gvks
+}
+
+// sortGVKs sorts the given GVKs by group, then version, then kind.
+func sortGVKs(gvks []schema.GroupVersionKind) {
+	sort.Slice(gvks, func(i, j int) bool {
+		if gvks[i].Group != gvks[j].Group {
+			return gvks[i].Group < gvks[j].Group
+		}
+		if gvks[i].Version != gvks[j].Version {
+			return gvks[i].Version < gvks[j].Version
+		}
+		return gvks[i].Kind < gvks[j].Kind
+	})
 }
 
-func New(scheme *runtime.Scheme) (CacheStores, error) {
-	stores := make(map[schema.GroupVersionKind]cache.Indexer)
+// CountAll returns the number of cached objects of every registered GVK.
+func (s *CacheStores) CountAll() map[schema.GroupVersionKind]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	for i := range supportedKinds {
-		gvk, err := gvkFromObject(supportedKinds[i], scheme)
-		if err != nil {
-			return CacheStores{}, err
+	counts := make(map[schema.GroupVersionKind]int, len(s.storesByGvk))
+	for gvk, store := range s.storesByGvk {
+		counts[gvk] = len(store.ListKeys())
+	}
+
+	return counts
+}
+
+// ListGroup calls into with a copy of every cached object whose GVK belongs to the given
+// group and version, e.g. for garbage-collection sweeps. The objects span every matching
+// kind and are passed in no particular order. into is called once the lock is released,
+// so it may use the cache. It returns ErrGvkNotFound if no registered GVK matches.
+func (s *CacheStores) ListGroup(group, version string, into func(runtime.Object)) error {
+	if into == nil {
+		return fmt.Errorf("cannot list group %s/%s into nil function", group, version)
+	}
+
+	gv := schema.GroupVersion{Group: group, Version: version}
+
+	var (
+		matched bool
+		objs    []runtime.Object
+	)
+	s.mu.RLock()
+	for gvk, store := range s.storesByGvk {
+		if gvk.GroupVersion() != gv {
+			continue
+		}
+		matched = true
+		for _, item := range store.List() {
+			obj, isObj := item.(runtime.Object)
+			if !isObj {
+				s.mu.RUnlock()
+				return fmt.Errorf("cache contained %T, which is not an Object", item)
+			}
+			obj = obj.DeepCopyObject()
+			obj.GetObjectKind().SetGroupVersionKind(gvk)
+			objs = append(objs, obj)
 		}
+	}
+	s.mu.RUnlock()
+
+	if !matched {
+		return ErrGvkNotFound
+	}
 
-		registerGvkIntoCache(*gvk, stores)
+	for _, obj := range objs {
+		into(obj)
 	}
 
-	return CacheStores{
-		storesByGvk: stores,
-		scheme:      scheme,
-	}, nil
+	return nil
 }
 
-// List only works with structured types; not working with partial objects or unstructured data
-func (s *CacheStores) List(out client.ObjectList, opts ...client.ListOption) error {
-	if out == nil {
+func (s *CacheStores) GetByType(t schema.GroupVersionKind) cache.Indexer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.storesByGvk[t]
+}
+
+// IndexField registers a field index for the GVK of the given object. It returns
+// ErrGvkNotFound if the GVK is not registered in the cache.
+//
+// Indexing a field again returns an error wrapping ErrIndexExists, as the cache can not tell
+// whether two functions extract the same values, closures created by the same function
+// literal may capture different state, and client-go can not replace the function of an
+// index. Setup code running more than once can ignore it with errors.Is, or call
+// DeleteIndex first. metadata.name and metadata.namespace are indexed on every GVK when it
+// is registered, so indexing them again is a no-op.
+//
+// A panic of extractValue is recovered and returned as an error, by IndexField if it
+// panics on an object already cached, and by the methods adding objects otherwise, in
+// which case the object is not stored.
+func (s *CacheStores) IndexField(obj client.Object, field string, extractValue client.IndexerFunc, opts ...IndexOption) error {
+	return s.indexField(obj, field, extractValue, "", opts...)
+}
+
+// indexField is IndexField for the indexes whose values are fully described by the given
+// spec, e.g. the JSONPath of IndexByJSONPath, which makes indexing a field again with the
+// same spec and options a no-op. An empty spec describes an opaque function.
+func (s *CacheStores) indexField(obj client.Object, field string, extractValue client.IndexerFunc, spec string, opts ...IndexOption) error {
+	if obj == nil {
 		return ErrNilObj
 	}
+	if extractValue == nil {
+		return fmt.Errorf("cannot index field %s with a nil function", field)
+	}
 
-	gvk, err := gvkFromObject(out, s.scheme)
+	gvk, err := s.gvkFor(obj)
 	if err != nil {
 		return err
 	}
 
-	gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	store := s.storesByGvk[*gvk]
 	if store == nil {
 		return ErrGvkNotFound
 	}
+	if isImplicitField(field) {
+		return nil
+	}
 
-	listOpts := client.ListOptions{}
-	listOpts.ApplyOptions(opts)
-
-	var objs []interface{}
+	indexOpts := indexOptions{}
+	for _, opt := range opts {
+		opt(&indexOpts)
+	}
 
-	switch {
-	case listOpts.FieldSelector != nil:
-		requiresExact := requiresExactMatch(listOpts.FieldSelector)
-		if !requiresExact {
-			return fmt.Errorf("non-exact field matches are not supported by the cache")
+	if indexOpts.namespacedOnly {
+		clusterScoped, err := s.isClusterScoped(*gvk)
+		if err != nil {
+			return err
+		}
+		if clusterScoped {
+			return fmt.Errorf("%s is cluster-scoped and can not be indexed by namespace only", gvk.Kind)
 		}
-		// list all objects by the field selector. If this is namespaced and we have one, ask for the
-		// namespaced index key. Otherwise, ask for the non-namespaced variant by using the fake "all namespaces"
-		// namespace.
-		objs, err = byIndexes(store, listOpts.FieldSelector.Requirements(), listOpts.Namespace)
-	case listOpts.Namespace != "":
-		objs, err = store.ByIndex(cache.NamespaceIndex, listOpts.Namespace)
-	default:
-		objs = store.List()
-	}
-	if err != nil {
-		return err
 	}
 
-	var labelSel labels.Selector
-	if listOpts.LabelSelector != nil {
-		labelSel = listOpts.LabelSelector
+	indexName := s.fieldIdxName(field)
+	if _, exists := store.GetIndexers()[indexName]; exists {
+		if spec == "" || s.indexSpecs[*gvk][indexName] != spec {
+			return fmt.Errorf("index with name %s is already registered: %w", indexName, ErrIndexExists)
+		}
+		if s.namespacedOnly[*gvk][indexName] != indexOpts.namespacedOnly {
+			return fmt.Errorf("index with name %s is already registered with different options", indexName)
+		}
+		return nil
 	}
 
-	limitSet := listOpts.Limit > 0
-
-	runtimeObjs := make([]runtime.Object, 0, len(objs))
-	for _, item := range objs {
-		// if the Limit option is set and the number of items
-		// listed exceeds this limit, then stop reading.
-		if limitSet && int64(len(runtimeObjs)) >= listOpts.Limit {
-			break
+	if err := s.indexByField(*gvk, store, field, extractValue, indexOpts); err != nil {
+		return err
+	}
+	if indexOpts.namespacedOnly {
+		if s.namespacedOnly == nil {
+			s.namespacedOnly = make(map[schema.GroupVersionKind]map[string]bool)
 		}
-		obj, isObj := item.(runtime.Object)
-		if !isObj {
-			return fmt.Errorf("cache contained %T, which is not an Object", item)
+		if s.namespacedOnly[*gvk] == nil {
+			s.namespacedOnly[*gvk] = make(map[string]bool)
 		}
-		meta, err := apimeta.Accessor(obj)
-		if err != nil {
-			return err
+		s.namespacedOnly[*gvk][indexName] = true
+	}
+
+	if spec != "" {
+		if s.indexSpecs == nil {
+			s.indexSpecs = make(map[schema.GroupVersionKind]map[string]string)
 		}
-		if labelSel != nil {
-			lbls := labels.Set(meta.GetLabels())
-			if !labelSel.Matches(lbls) {
-				continue
-			}
+		if s.indexSpecs[*gvk] == nil {
+			s.indexSpecs[*gvk] = make(map[string]string)
 		}
-
-		var outObj runtime.Object
-		outObj = obj.DeepCopyObject()
-		outObj.GetObjectKind().SetGroupVersionKind(*gvk)
-		runtimeObjs = append(runtimeObjs, outObj)
+		s.indexSpecs[*gvk][indexName] = spec
 	}
+	s.logIndexRegistered(*gvk, indexName)
 
-	return apimeta.SetList(out, runtimeObjs)
+	return nil
 }
 
-func (s *CacheStores) Get(obj client.Object) (item interface{}, exists bool, err error) {
+// DeleteIndex removes the field index registered by IndexField for the given field.
+//
+// client-go indexers can not drop an index, so the store is rebuilt with the remaining
+// indexes and every cached object is indexed again, which is O(n) in the number of objects
+// of the GVK and blocks every other cache operation meanwhile. Indexers previously obtained
+// through GetByType are not updated.
+func (s *CacheStores) DeleteIndex(obj client.Object, field string) error {
 	if obj == nil {
-		return nil, false, fmt.Errorf("cannot add nil object")
+		return ErrNilObj
 	}
 
-	gvk, err := gvkFromObject(obj, s.scheme)
+	gvk, err := s.gvkFor(obj)
 	if err != nil {
-		return nil, false, err
+		return err
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	store := s.storesByGvk[*gvk]
 	if store == nil {
-		return nil, false, nil
+		return ErrGvkNotFound
 	}
 
-	return store.GetByKey(client.ObjectKeyFromObject(obj).String())
-}
-
-func (s *CacheStores) Delete(obj client.Object) error {
-	if obj == nil {
-		return fmt.Errorf("cannot delete nil object")
+	if isImplicitField(field) {
+		return fmt.Errorf("index on %s can not be deleted", field)
 	}
 
-	gvk, err := gvkFromObject(obj, s.scheme)
-	if err != nil {
-		return err
+	indexName := s.fieldIdxName(field)
+	indexers := cache.Indexers{}
+	for name, fn := range store.GetIndexers() {
+		indexers[name] = fn
 	}
+	if _, exists := indexers[indexName]; !exists {
+		return fmt.Errorf("index with name %s does not exist", indexName)
+	}
+	delete(indexers, indexName)
+	delete(s.indexSpecs[*gvk], indexName)
+	delete(s.namespacedOnly[*gvk], indexName)
 
-	store := s.storesByGvk[*gvk]
-	if store == nil {
-		return nil
+	newStore := cache.NewIndexer(s.keyFunc(*gvk), indexers)
+	if err := newStore.Replace(store.List(), ""); err != nil {
+		return err
 	}
+	s.storesByGvk[*gvk] = newStore
+	s.log().V(1).Info("deleted index", "gvk", gvk.String(), "index", indexName)
 
-	return store.Delete(obj)
+	return nil
 }
 
-func (s *CacheStores) Add(obj client.Object) error {
+// IndexLabel registers an index on the given label key for the GVK of the given object.
+// List uses the index instead of scanning the store when its label selector has a
+// `key=value` requirement on an indexed label, intersected with the field indexes if it has a
+// field selector as well. The other requirements are matched against the indexed objects.
+func (s *CacheStores) IndexLabel(obj client.Object, labelKey string) error {
 	if obj == nil {
-		return fmt.Errorf("cannot add nil object")
+		return ErrNilObj
 	}
 
-	gvk, err := gvkFromObject(obj, s.scheme)
+	gvk, err := s.gvkFor(obj)
 	if err != nil {
 		return err
 	}
 
+	// the indexers of the store are read by List, so they are added under the write lock.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	store := s.storesByGvk[*gvk]
 	if store == nil {
-		store = registerGvkIntoCache(*gvk, s.storesByGvk)
+		return ErrGvkNotFound
 	}
-	//obj.GetObjectKind().SetGroupVersionKind(*gvk)
-
-	return store.Add(obj)
-}
-
-func (s *CacheStores) GetByType(t schema.GroupVersionKind) cache.Indexer {
-	return s.storesByGvk[t]
-}
 
-func (s *CacheStores) IndexField(obj client.Object, field string, extractValue client.IndexerFunc) error {
-	if obj == nil {
+	// the index of a label key is always the same, so registering it again is a no-op.
+	if _, exists := store.GetIndexers()[labelIdxName(labelKey)]; exists {
 		return nil
 	}
 
-	gvk, err := gvkFromObject(obj, s.scheme)
-	if err != nil {
-		return nil
+	extractValue := func(o client.Object) []string {
+		val, ok := o.GetLabels()[labelKey]
+		if !ok {
+			return nil
+		}
+		return []string{val}
 	}
 
-	store := s.storesByGvk[*gvk]
-	if store == nil {
-		return nil
+	err = store.AddIndexers(
+		cache.Indexers{
+			labelIdxName(labelKey): s.indexFuncFor(*gvk, extractValue),
+		},
+	)
+	if err != nil {
+		return err
 	}
+	s.logIndexRegistered(*gvk, labelIdxName(labelKey))
 
-	return indexByField(store, field, extractValue)
+	return nil
 }
 
 // allNamespacesNamespace is used as the "namespace" when we want to list across all namespaces.
@@ -209,15 +1773,56 @@ const allNamespacesNamespace = "__all"
 
 // keyToNamespacedKey prefixes the given index key with a namespace
 // for use in field selector indexes.
+//
+// The namespace is prefixed with its length so that values containing "/" can not make
+// two namespace/value pairs share a key, e.g. "a"/"b/c" and "a/b"/"c". The
+// all-namespaces key starts with "_" instead of a digit, so it never matches a namespace.
 func keyToNamespacedKey(ns string, baseKey string) string {
 	if ns != "" {
-		return ns + "/" + baseKey
+		return strconv.Itoa(len(ns)) + ":" + ns + "/" + baseKey
 	}
 	return allNamespacesNamespace + "/" + baseKey
 }
 
-func indexByField(store cache.Indexer, field string, extractValue client.IndexerFunc) error {
-	indexFunc := func(objRaw interface{}) ([]string, error) {
+// namespacedKeyValue returns the value of an index key built by keyToNamespacedKey.
+func namespacedKeyValue(key string) string {
+	if rest, ok := strings.CutPrefix(key, allNamespacesNamespace+"/"); ok {
+		return rest
+	}
+
+	length, rest, ok := strings.Cut(key, ":")
+	if !ok {
+		return key
+	}
+	n, err := strconv.Atoi(length)
+	if err != nil || n < 0 || len(rest) < n+1 {
+		return key
+	}
+
+	return rest[n+1:]
+}
+
+// indexByField adds a field index to the store once the function is known not to fail on
+// the objects already stored.
+func (s *CacheStores) indexByField(gvk schema.GroupVersionKind, store cache.Indexer, field string, extractValue client.IndexerFunc, opts indexOptions) error {
+	indexFunc := s.indexFuncFor(gvk, extractValue)
+	if opts.namespacedOnly {
+		indexFunc = namespaceOnlyIndexFunc(extractValue)
+	}
+	indexers := cache.Indexers{
+		s.fieldIdxName(field): indexFunc,
+	}
+	if err := s.checkIndexable(indexers, store.List()...); err != nil {
+		return err
+	}
+
+	return store.AddIndexers(indexers)
+}
+
+// namespacedIndexFunc returns an index function indexing the values extracted from an
+// object under both its namespaced key and its all-namespaces key.
+func namespacedIndexFunc(extractValue client.IndexerFunc) cache.IndexFunc {
+	return func(objRaw interface{}) ([]string, error) {
 		obj, isObj := objRaw.(client.Object)
 		if !isObj {
 			return nil, fmt.Errorf("object of type %T is not an Object", objRaw)
@@ -246,25 +1851,66 @@ func indexByField(store cache.Indexer, field string, extractValue client.Indexer
 
 		return vals, nil
 	}
+}
 
-	return store.AddIndexers(
-		cache.Indexers{
-			fieldIdxName(field): indexFunc,
-		},
-	)
+// namespaceOnlyIndexFunc returns an index function indexing the values extracted from an
+// object under its namespaced key only. It fails on the objects without a namespace, which
+// could then only be found across all namespaces, where the index can not be queried.
+func namespaceOnlyIndexFunc(extractValue client.IndexerFunc) cache.IndexFunc {
+	return func(objRaw interface{}) ([]string, error) {
+		obj, isObj := objRaw.(client.Object)
+		if !isObj {
+			return nil, fmt.Errorf("object of type %T is not an Object", objRaw)
+		}
+		meta, err := apimeta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		if meta.GetNamespace() == "" {
+			return nil, fmt.Errorf("namespaced only index can not index objects without a namespace")
+		}
+
+		rawVals := extractValue(obj)
+		vals := make([]string, len(rawVals))
+		for i, rawVal := range rawVals {
+			vals[i] = keyToNamespacedKey(meta.GetNamespace(), rawVal)
+		}
+
+		return vals, nil
+	}
+}
+
+// FieldIndexPrefix prefixes the names of the client-go indexes backing field indexes, to
+// tell them apart from the other indexes of a store. It is the default of the caches
+// created afterwards, see WithIndexPrefix, as the indexes of existing stores are not renamed.
+var FieldIndexPrefix = "field:"
+
+func (s *CacheStores) fieldIdxName(field string) string {
+	return s.indexPrefix + field
 }
 
-func fieldIdxName(field string) string {
-	return "tyk_f:" + field
+// labelIdxPrefix and compositeIdxPrefix prefix the names of the client-go indexes backing
+// label and composite indexes, which is why they can not be used as field index prefixes.
+const (
+	labelIdxPrefix     = "label:"
+	compositeIdxPrefix = "composite:"
+)
+
+func labelIdxName(label string) string {
+	return labelIdxPrefix + label
 }
 
-func registerGvkIntoCache(gvk schema.GroupVersionKind, c cacheStore) cache.Indexer {
-	newCache := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
-	c[gvk] = newCache
+func (s *CacheStores) registerGvkIntoCache(gvk schema.GroupVersionKind, keyFunc cache.KeyFunc) cache.Indexer {
+	indexers := s.implicitFieldIndexers(gvk)
+	indexers[cache.NamespaceIndex] = cache.MetaNamespaceIndexFunc
+	newCache := cache.NewIndexer(keyFunc, indexers)
+	s.storesByGvk[gvk] = newCache
 	return newCache
 }
 
-// requiresExactMatch checks if the given field selector is of the form `k=v` or `k==v`.
+// requiresExactMatch checks if the given field selector is of the form `k=v`, `k==v`,
+// `k!=v`, `k in (v1,v2)`, `k notin (v1,v2)` or `k`, i.e. every requirement can be answered
+// by looking up exact values in the field indexes.
 func requiresExactMatch(sel fields.Selector) bool {
 	reqs := sel.Requirements()
 	if len(reqs) == 0 {
@@ -272,14 +1918,74 @@ func requiresExactMatch(sel fields.Selector) bool {
 	}
 
 	for _, req := range reqs {
-		if req.Operator != selection.Equals && req.Operator != selection.DoubleEquals {
+		switch req.Operator {
+		case selection.Equals, selection.DoubleEquals, selection.NotEquals, selection.In, selection.NotIn, selection.Exists:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// sortByName sorts the given objects by namespace, then by name.
+func sortByName(objs []interface{}) error {
+	return sortByPageKey(objs, pageKeyFunc(nil))
+}
+
+// labelIndexRequirement returns the label index and value to look up if the given label
+// selector has a `k=v` or `k==v` requirement on a label indexed by IndexLabel, the first
+// one if there are several. The other requirements are left to the caller to evaluate.
+func labelIndexRequirement(indexer cache.Indexer, sel labels.Selector) (string, string, bool) {
+	if sel == nil {
+		return "", "", false
+	}
+
+	reqs, selectable := sel.Requirements()
+	if !selectable {
+		return "", "", false
+	}
+
+	indexers := indexer.GetIndexers()
+	for _, req := range reqs {
+		if req.Operator() != selection.Equals && req.Operator() != selection.DoubleEquals {
+			continue
+		}
+
+		indexName := labelIdxName(req.Key())
+		if _, exists := indexers[indexName]; exists {
+			return indexName, req.Values().List()[0], true
+		}
+	}
+
+	return "", "", false
+}
+
+// labelAbsentFromIndex reports whether the given label selector is a single `!k` requirement
+// on a label indexed by IndexLabel that no object of the namespace carries, in which case
+// the selector matches every object and does not need to be evaluated.
+func labelAbsentFromIndex(indexer cache.Indexer, sel labels.Selector, namespace string) bool {
+	reqs, selectable := sel.Requirements()
+	if !selectable || len(reqs) != 1 || reqs[0].Operator() != selection.DoesNotExist {
+		return false
+	}
+
+	indexName := labelIdxName(reqs[0].Key())
+	if _, exists := indexer.GetIndexers()[indexName]; !exists {
+		return false
+	}
+
+	// labels with an empty value are indexed under the bare prefix, which is a match as well.
+	prefix := keyToNamespacedKey(namespace, "")
+	for _, indexedValue := range indexer.ListIndexFuncValues(indexName) {
+		if strings.HasPrefix(indexedValue, prefix) {
 			return false
 		}
 	}
+
 	return true
 }
 
-func byIndexes(indexer cache.Indexer, requires fields.Requirements, namespace string) ([]interface{}, error) {
+func (s *CacheStores) byIndexes(indexer cache.Indexer, requires fields.Requirements, namespace string) ([]interface{}, error) {
 	var (
 		err  error
 		objs []interface{}
@@ -287,20 +1993,47 @@ func byIndexes(indexer cache.Indexer, requires fields.Requirements, namespace st
 	)
 	indexers := indexer.GetIndexers()
 	for idx, req := range requires {
-		indexName := fieldIdxName(req.Field)
-		indexedValue := keyToNamespacedKey(namespace, req.Value)
+		indexName := s.fieldIdxName(req.Field)
+		values := requirementValues(req)
+		if req.Operator == selection.Exists {
+			if _, exist := indexers[indexName]; !exist {
+				return nil, fmt.Errorf("index with name %s does not exist", indexName)
+			}
+			// the index only holds the values emitted by the objects, so the field
+			// exists for the objects indexed under any of them.
+			values = indexedRawValues(indexer, indexName, namespace)
+		}
+		indexedValues := make([]string, 0, len(values))
+		for _, v := range values {
+			indexedValues = append(indexedValues, keyToNamespacedKey(namespace, v))
+		}
+		exclude := req.Operator == selection.NotIn || req.Operator == selection.NotEquals
+
 		if idx == 0 {
-			// we use first require to get snapshot data
-			// TODO(halfcrazy): use complicated index when client-go provides byIndexes
-			// https://github.com/kubernetes/kubernetes/issues/109329
-			objs, err = indexer.ByIndex(indexName, indexedValue)
-			if err != nil {
-				return nil, err
+			if !exclude {
+				// we use first require to get snapshot data
+				// TODO(halfcrazy): use complicated index when client-go provides byIndexes
+				// https://github.com/kubernetes/kubernetes/issues/109329
+				objs, err = byIndexValues(indexer, indexName, indexedValues)
+				if err != nil {
+					return nil, err
+				}
+				if len(objs) == 0 {
+					return nil, nil
+				}
+				continue
 			}
-			if len(objs) == 0 {
-				return nil, nil
+
+			// a negative requirement can not be answered by the index, so start from
+			// every object in the namespace and filter out the matching ones below.
+			if namespace != "" {
+				objs, err = indexer.ByIndex(cache.NamespaceIndex, namespace)
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				objs = indexer.List()
 			}
-			continue
 		}
 		fn, exist := indexers[indexName]
 		if !exist {
@@ -312,11 +2045,13 @@ func byIndexes(indexer cache.Indexer, requires fields.Requirements, namespace st
 			if err != nil {
 				return nil, err
 			}
-			for _, val := range vals {
-				if val == indexedValue {
-					filteredObjects = append(filteredObjects, obj)
-					break
-				}
+			var matches bool
+			matches, err = matchesIndexedValues(obj, vals, values, namespace)
+			if err != nil {
+				return nil, err
+			}
+			if matches != exclude {
+				filteredObjects = append(filteredObjects, obj)
 			}
 		}
 		if len(filteredObjects) == 0 {
@@ -328,6 +2063,99 @@ func byIndexes(indexer cache.Indexer, requires fields.Requirements, namespace st
 	return objs, nil
 }
 
+// indexedRawValues returns the non-empty values stored in the given field index for the given
+// namespace, or for all namespaces if it is empty, without their namespace prefix.
+func indexedRawValues(indexer cache.Indexer, indexName string, namespace string) []string {
+	prefix := keyToNamespacedKey(namespace, "")
+
+	var values []string
+	for _, indexedValue := range indexer.ListIndexFuncValues(indexName) {
+		if len(indexedValue) > len(prefix) && strings.HasPrefix(indexedValue, prefix) {
+			values = append(values, strings.TrimPrefix(indexedValue, prefix))
+		}
+	}
+
+	return values
+}
+
+// intersectObjects returns the objects of a which are also in b, in the order of a. The
+// indexer returns the stored pointers, which identify the objects.
+func intersectObjects(a, b []interface{}) []interface{} {
+	inB := make(map[interface{}]struct{}, len(b))
+	for _, obj := range b {
+		inB[obj] = struct{}{}
+	}
+
+	var objs []interface{}
+	for _, obj := range a {
+		if _, ok := inB[obj]; ok {
+			objs = append(objs, obj)
+		}
+	}
+
+	return objs
+}
+
+// byIndexValues returns the union of the objects indexed under any of the given values.
+func byIndexValues(indexer cache.Indexer, indexName string, indexedValues []string) ([]interface{}, error) {
+	if len(indexedValues) == 1 {
+		return indexer.ByIndex(indexName, indexedValues[0])
+	}
+
+	var objs []interface{}
+	// the indexer returns the stored pointers, which identify objects whatever their key function.
+	seen := make(map[interface{}]struct{})
+	for _, indexedValue := range indexedValues {
+		items, err := indexer.ByIndex(indexName, indexedValue)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if _, ok := seen[item]; ok {
+				continue
+			}
+			seen[item] = struct{}{}
+			objs = append(objs, item)
+		}
+	}
+
+	return objs, nil
+}
+
+// requirementValues returns the values of the given field requirement. Set-based
+// requirements carry their values as a comma separated list, see FieldIn.
+func requirementValues(req fields.Requirement) []string {
+	if req.Operator == selection.In || req.Operator == selection.NotIn {
+		return strings.Split(req.Value, ",")
+	}
+	return []string{req.Value}
+}
+
+// matchesIndexedValues reports whether the index values computed for obj contain one of
+// the given values keyed for the listed namespace. When listing across all namespaces,
+// the values keyed for the namespace of obj are accepted as well, so that requirements
+// are intersected correctly whichever key variant the index emitted.
+func matchesIndexedValues(obj interface{}, vals []string, values []string, namespace string) (bool, error) {
+	var objNamespace string
+	if namespace == "" {
+		meta, err := apimeta.Accessor(obj)
+		if err != nil {
+			return false, err
+		}
+		objNamespace = meta.GetNamespace()
+	}
+
+	for _, v := range values {
+		if slices.Contains(vals, keyToNamespacedKey(namespace, v)) {
+			return true, nil
+		}
+		if objNamespace != "" && slices.Contains(vals, keyToNamespacedKey(objNamespace, v)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func gvkFromObject(obj runtime.Object, scheme *runtime.Scheme) (*schema.GroupVersionKind, error) {
 	if obj == nil {
 		return nil, fmt.Errorf("cannot get nil object")
@@ -336,6 +2164,19 @@ func gvkFromObject(obj runtime.Object, scheme *runtime.Scheme) (*schema.GroupVer
 		return nil, fmt.Errorf("cannot get scheme nil object")
 	}
 
+	// a structured type may be registered under several versions, in which case the
+	// version set in its TypeMeta tells which one it is.
+	if gvk := obj.GetObjectKind().GroupVersionKind(); representationOf(obj) == structured && gvk.Version != "" && gvk.Kind != "" {
+		gvks, _, err := scheme.ObjectKinds(obj)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(gvks, gvk) {
+			return nil, fmt.Errorf("object of type %T has TypeMeta %s, which is not registered for it in the scheme", obj, gvk)
+		}
+		return &gvk, nil
+	}
+
 	gvk, err := apiutil.GVKForObject(obj, scheme)
 	if err != nil {
 		return nil, err
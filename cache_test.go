@@ -0,0 +1,1112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	util "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	util.Must(appsv1.AddToScheme(scheme))
+	util.Must(corev1.AddToScheme(scheme))
+	util.Must(rbacv1.AddToScheme(scheme))
+	return scheme
+}
+
+// newTestCache returns a cache with the Deployment kind registered.
+func newTestCache(t testing.TB, opts ...Option) *CacheStores {
+	t.Helper()
+
+	c, err := New(newTestScheme(), opts...)
+	if err != nil {
+		t.Fatalf("failed to create the cache: %v", err)
+	}
+	if err := c.RegisterKind(&appsv1.Deployment{}); err != nil {
+		t.Fatalf("failed to register deployments: %v", err)
+	}
+
+	return c
+}
+
+func deployment(namespace, name string, labels map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    labels,
+		},
+	}
+}
+
+func mustAdd(t testing.TB, c *CacheStores, objs ...client.Object) {
+	t.Helper()
+
+	for _, obj := range objs {
+		if err := c.Add(obj); err != nil {
+			t.Fatalf("failed to add %s: %v", client.ObjectKeyFromObject(obj), err)
+		}
+	}
+}
+
+// listDeploymentKeys lists the cached deployments and returns their namespace/name keys, in
+// the order they were listed.
+func listDeploymentKeys(t testing.TB, c *CacheStores, opts ...client.ListOption) []string {
+	t.Helper()
+
+	list := &appsv1.DeploymentList{}
+	if err := c.List(list, opts...); err != nil {
+		t.Fatalf("failed to list deployments: %v", err)
+	}
+
+	keys := make([]string, 0, len(list.Items))
+	for i := range list.Items {
+		keys = append(keys, client.ObjectKeyFromObject(&list.Items[i]).String())
+	}
+	return keys
+}
+
+func TestListInNamespace(t *testing.T) {
+	c := newTestCache(t)
+	mustAdd(t, c,
+		deployment("default", "a", nil),
+		deployment("default", "b", nil),
+		deployment("other", "a", nil),
+	)
+
+	tests := []struct {
+		namespace string
+		want      []string
+	}{
+		{namespace: "default", want: []string{"default/a", "default/b"}},
+		{namespace: "other", want: []string{"other/a"}},
+		{namespace: "missing", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.namespace, func(t *testing.T) {
+			got := listDeploymentKeys(t, c, client.InNamespace(tt.namespace))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// indexTier indexes the "tier" annotation of deployments under the "tier" field.
+func indexTier(t testing.TB, c *CacheStores) {
+	t.Helper()
+
+	err := c.IndexField(&appsv1.Deployment{}, "tier", func(o client.Object) []string {
+		if tier := o.GetAnnotations()["tier"]; tier != "" {
+			return []string{tier}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to index tiers: %v", err)
+	}
+}
+
+func withTier(obj *appsv1.Deployment, tier string) *appsv1.Deployment {
+	obj.Annotations = map[string]string{"tier": tier}
+	return obj
+}
+
+func TestListFieldIn(t *testing.T) {
+	c := newTestCache(t)
+	indexTier(t, c)
+	mustAdd(t, c,
+		withTier(deployment("a", "web", nil), "frontend"),
+		withTier(deployment("a", "db", nil), "backend"),
+		withTier(deployment("a", "cron", nil), "batch"),
+		withTier(deployment("b", "web", nil), "frontend"),
+		// objects without a namespace are indexed as cluster-scoped ones.
+		withTier(deployment("", "proxy", nil), "frontend"),
+		withTier(deployment("", "queue", nil), "batch"),
+	)
+
+	tests := []struct {
+		name     string
+		selector fields.Selector
+		opts     []client.ListOption
+		want     []string
+	}{
+		{
+			name:     "in across namespaces",
+			selector: FieldIn("tier", "frontend", "backend"),
+			want:     []string{"/proxy", "a/db", "a/web", "b/web"},
+		},
+		{
+			name:     "in within a namespace",
+			selector: FieldIn("tier", "frontend", "backend"),
+			opts:     []client.ListOption{client.InNamespace("a")},
+			want:     []string{"a/db", "a/web"},
+		},
+		{
+			name:     "in with unknown values",
+			selector: FieldIn("tier", "unknown", "batch"),
+			want:     []string{"/queue", "a/cron"},
+		},
+		{
+			name:     "not in across namespaces",
+			selector: FieldNotIn("tier", "frontend", "backend"),
+			want:     []string{"/queue", "a/cron"},
+		},
+		{
+			name:     "not in within a namespace",
+			selector: FieldNotIn("tier", "batch"),
+			opts:     []client.ListOption{client.InNamespace("b")},
+			want:     []string{"b/web"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]client.ListOption{client.MatchingFieldsSelector{Selector: tt.selector}}, tt.opts...)
+			got := listDeploymentKeys(t, c, opts...)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListFieldInClusterScoped(t *testing.T) {
+	c := newTestCache(t)
+	if err := c.RegisterKind(&rbacv1.ClusterRole{}); err != nil {
+		t.Fatalf("failed to register cluster roles: %v", err)
+	}
+	for _, name := range []string{"admin", "edit", "view"} {
+		mustAdd(t, c, &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+
+	list := &rbacv1.ClusterRoleList{}
+	err := c.List(list, client.MatchingFieldsSelector{Selector: FieldIn("metadata.name", "admin", "view")})
+	if err != nil {
+		t.Fatalf("failed to list cluster roles: %v", err)
+	}
+
+	var got []string
+	for _, role := range list.Items {
+		got = append(got, role.Name)
+	}
+	if want := []string{"admin", "view"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGetReturnsCopy(t *testing.T) {
+	c := newTestCache(t)
+	mustAdd(t, c, deployment("default", "web", map[string]string{"app": "web"}))
+
+	item, exists, err := c.Get(deployment("default", "web", nil))
+	if err != nil || !exists {
+		t.Fatalf("failed to get the deployment: exists %v, err %v", exists, err)
+	}
+	got := item.(*appsv1.Deployment)
+	got.Labels["app"] = "mutated"
+	got.Spec.Paused = true
+
+	item, _, err = c.Get(deployment("default", "web", nil))
+	if err != nil {
+		t.Fatalf("failed to get the deployment again: %v", err)
+	}
+	again := item.(*appsv1.Deployment)
+	if again.Labels["app"] != "web" || again.Spec.Paused {
+		t.Errorf("mutating a Get result changed the cache: labels %v, paused %v", again.Labels, again.Spec.Paused)
+	}
+}
+
+func TestListLimitIsDeterministic(t *testing.T) {
+	c := newTestCache(t)
+	for _, name := range []string{"e", "c", "a", "d", "b"} {
+		mustAdd(t, c, deployment("default", name, nil))
+	}
+
+	for i := 0; i < 10; i++ {
+		got := listDeploymentKeys(t, c, client.Limit(1))
+		if want := []string{"default/a"}; !slices.Equal(got, want) {
+			t.Fatalf("list %d: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func withResourceVersion(obj *appsv1.Deployment, rv string) *appsv1.Deployment {
+	obj.ResourceVersion = rv
+	return obj
+}
+
+func TestResourceVersionBumpedOnOverwrite(t *testing.T) {
+	gvk := appsv1.SchemeGroupVersion.WithKind("Deployment")
+
+	tests := []struct {
+		name      string
+		overwrite func(c *CacheStores, obj *appsv1.Deployment) error
+	}{
+		{name: "add", overwrite: func(c *CacheStores, obj *appsv1.Deployment) error {
+			return c.Add(obj)
+		}},
+		{name: "upsert", overwrite: func(c *CacheStores, obj *appsv1.Deployment) error {
+			_, _, err := c.Upsert(obj)
+			return err
+		}},
+		{name: "add all", overwrite: func(c *CacheStores, obj *appsv1.Deployment) error {
+			return c.AddAll(obj)
+		}},
+		{name: "replace", overwrite: func(c *CacheStores, obj *appsv1.Deployment) error {
+			return c.Replace(gvk, []client.Object{obj}, "")
+		}},
+		{name: "update", overwrite: func(c *CacheStores, obj *appsv1.Deployment) error {
+			return c.Update(obj)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(t)
+			mustAdd(t, c, withResourceVersion(deployment("default", "web", nil), "5"))
+
+			// a stale or missing resource version is replaced by the next one.
+			if err := tt.overwrite(c, deployment("default", "web", nil)); err != nil {
+				t.Fatalf("failed to overwrite the deployment: %v", err)
+			}
+			if rv := getResourceVersion(t, c); rv != "6" {
+				t.Errorf("got resource version %q, want 6", rv)
+			}
+		})
+	}
+
+	t.Run("greater resource version is kept", func(t *testing.T) {
+		c := newTestCache(t)
+		mustAdd(t, c,
+			withResourceVersion(deployment("default", "web", nil), "5"),
+			withResourceVersion(deployment("default", "web", nil), "42"),
+		)
+		if rv := getResourceVersion(t, c); rv != "42" {
+			t.Errorf("got resource version %q, want 42", rv)
+		}
+	})
+}
+
+func getResourceVersion(t testing.TB, c *CacheStores) string {
+	t.Helper()
+
+	item, exists, err := c.Get(deployment("default", "web", nil))
+	if err != nil || !exists {
+		t.Fatalf("failed to get the deployment: exists %v, err %v", exists, err)
+	}
+	return item.(client.Object).GetResourceVersion()
+}
+
+func TestListTwoFieldIndexes(t *testing.T) {
+	c := newTestCache(t)
+	indexTier(t, c)
+	err := c.IndexField(&appsv1.Deployment{}, "app", func(o client.Object) []string {
+		return []string{o.GetLabels()["app"]}
+	})
+	if err != nil {
+		t.Fatalf("failed to index apps: %v", err)
+	}
+	mustAdd(t, c,
+		withTier(deployment("a", "web", map[string]string{"app": "shop"}), "frontend"),
+		withTier(deployment("a", "db", map[string]string{"app": "shop"}), "backend"),
+		withTier(deployment("b", "web", map[string]string{"app": "shop"}), "frontend"),
+		withTier(deployment("b", "blog", map[string]string{"app": "blog"}), "frontend"),
+	)
+
+	tests := []struct {
+		name string
+		opts []client.ListOption
+		want []string
+	}{
+		{
+			name: "all namespaces",
+			want: []string{"a/web", "b/web"},
+		},
+		{
+			name: "one namespace",
+			opts: []client.ListOption{client.InNamespace("b")},
+			want: []string{"b/web"},
+		},
+		{
+			name: "namespace without matches",
+			opts: []client.ListOption{client.InNamespace("c")},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]client.ListOption{client.MatchingFields{"tier": "frontend", "app": "shop"}}, tt.opts...)
+			got := listDeploymentKeys(t, c, opts...)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetReturnsGVK(t *testing.T) {
+	c := newTestCache(t)
+	// the deployment is added without TypeMeta.
+	obj := deployment("default", "web", nil)
+	mustAdd(t, c, obj)
+
+	if gvk := obj.GetObjectKind().GroupVersionKind(); !gvk.Empty() {
+		t.Errorf("Add set the GVK %s of the caller's object", gvk)
+	}
+
+	item, exists, err := c.Get(deployment("default", "web", nil))
+	if err != nil || !exists {
+		t.Fatalf("failed to get the deployment: exists %v, err %v", exists, err)
+	}
+	want := appsv1.SchemeGroupVersion.WithKind("Deployment")
+	if gvk := item.(client.Object).GetObjectKind().GroupVersionKind(); gvk != want {
+		t.Errorf("got GVK %s, want %s", gvk, want)
+	}
+}
+
+func TestListFieldExists(t *testing.T) {
+	c := newTestCache(t)
+	indexTier(t, c)
+	mustAdd(t, c,
+		withTier(deployment("a", "web", nil), "frontend"),
+		withTier(deployment("b", "db", nil), "backend"),
+		deployment("a", "cron", nil),
+		withTier(deployment("a", "empty", nil), ""),
+	)
+
+	tests := []struct {
+		name string
+		opts []client.ListOption
+		want []string
+	}{
+		{name: "all namespaces", want: []string{"a/web", "b/db"}},
+		{name: "one namespace", opts: []client.ListOption{client.InNamespace("a")}, want: []string{"a/web"}},
+		{name: "namespace without the annotation", opts: []client.ListOption{client.InNamespace("c")}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]client.ListOption{client.MatchingFieldsSelector{Selector: FieldExists("tier")}}, tt.opts...)
+			got := listDeploymentKeys(t, c, opts...)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzKeyToNamespacedKey(f *testing.F) {
+	f.Add("a", "b/c", "a/b", "c")
+	f.Add("", "v", "__all", "v")
+	f.Add("1:a", "b", "1", "a/b")
+	f.Add("ns", "", "", "ns/")
+
+	f.Fuzz(func(t *testing.T, ns1, val1, ns2, val2 string) {
+		if ns1 == ns2 && val1 == val2 {
+			return
+		}
+		if key := keyToNamespacedKey(ns1, val1); key == keyToNamespacedKey(ns2, val2) {
+			t.Errorf("(%q, %q) and (%q, %q) share the key %q", ns1, val1, ns2, val2, key)
+		}
+	})
+}
+
+func FuzzNamespacedKeyValue(f *testing.F) {
+	f.Add("", "v")
+	f.Add("ns", "a/b")
+	f.Add("__all", "__all/v")
+	f.Add("12:x", "3:y/z")
+
+	f.Fuzz(func(t *testing.T, ns, val string) {
+		if got := namespacedKeyValue(keyToNamespacedKey(ns, val)); got != val {
+			t.Errorf("the key of (%q, %q) returns the value %q", ns, val, got)
+		}
+	})
+}
+
+func TestListLabelSelectors(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		c := newTestCache(t)
+		if indexed {
+			if err := c.IndexLabel(&appsv1.Deployment{}, "deprecated"); err != nil {
+				t.Fatalf("failed to index the label: %v", err)
+			}
+		}
+		mustAdd(t, c,
+			deployment("a", "web", map[string]string{"app": "web"}),
+			deployment("a", "old", map[string]string{"app": "web", "deprecated": "true"}),
+			deployment("a", "db", map[string]string{"app": "db"}),
+			deployment("a", "bare", nil),
+			deployment("a", "empty", map[string]string{}),
+			deployment("b", "web", map[string]string{"app": "web"}),
+		)
+
+		tests := []struct {
+			selector  string
+			namespace string
+			want      []string
+		}{
+			{selector: "!deprecated", want: []string{"a/bare", "a/db", "a/empty", "a/web", "b/web"}},
+			{selector: "!deprecated", namespace: "b", want: []string{"b/web"}},
+			{selector: "!missing", want: []string{"a/bare", "a/db", "a/empty", "a/old", "a/web", "b/web"}},
+			{selector: "app in (web,db)", want: []string{"a/db", "a/old", "a/web", "b/web"}},
+			{selector: "app in (web)", namespace: "a", want: []string{"a/old", "a/web"}},
+			// objects without the label match a NotIn requirement.
+			{selector: "app notin (web)", want: []string{"a/bare", "a/db", "a/empty"}},
+			{selector: "app notin (web),!deprecated", namespace: "a", want: []string{"a/bare", "a/db", "a/empty"}},
+			{selector: "app in (web),!deprecated", want: []string{"a/web", "b/web"}},
+		}
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("indexed %v %s in %q", indexed, tt.selector, tt.namespace), func(t *testing.T) {
+				selector, err := labels.Parse(tt.selector)
+				if err != nil {
+					t.Fatalf("failed to parse the selector: %v", err)
+				}
+				got := listDeploymentKeys(t, c,
+					client.MatchingLabelsSelector{Selector: selector},
+					client.InNamespace(tt.namespace),
+				)
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestIndexNamespacedOnly(t *testing.T) {
+	c := newTestCache(t)
+	err := c.IndexField(&appsv1.Deployment{}, "tier", func(o client.Object) []string {
+		return []string{o.GetAnnotations()["tier"]}
+	}, IndexNamespacedOnly())
+	if err != nil {
+		t.Fatalf("failed to index tiers: %v", err)
+	}
+	mustAdd(t, c,
+		withTier(deployment("a", "web", nil), "frontend"),
+		withTier(deployment("a", "db", nil), "backend"),
+		withTier(deployment("b", "web", nil), "frontend"),
+	)
+
+	got := listDeploymentKeys(t, c, client.InNamespace("a"), client.MatchingFields{"tier": "frontend"})
+	if want := []string{"a/web"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	stats, err := c.Stats(&appsv1.Deployment{})
+	if err != nil {
+		t.Fatalf("failed to get the stats: %v", err)
+	}
+	// each value is indexed under its namespace only.
+	if n := stats.Indexes[FieldIndexPrefix+"tier"]; n != 3 {
+		t.Errorf("got %d index keys, want 3", n)
+	}
+
+	err = c.List(&appsv1.DeploymentList{}, client.MatchingFields{"tier": "frontend"})
+	if err == nil {
+		t.Error("expected an error listing across namespaces")
+	}
+	// objects without a namespace could not be found through the index.
+	if err := c.Add(withTier(deployment("", "proxy", nil), "frontend")); err == nil {
+		t.Error("expected an error adding a deployment without a namespace")
+	}
+}
+
+func TestIndexNamespacedOnlyWithoutNamespace(t *testing.T) {
+	c := newTestCache(t)
+	mustAdd(t, c, &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "admin"}})
+
+	err := c.IndexField(&rbacv1.ClusterRole{}, "name", func(o client.Object) []string {
+		return []string{o.GetName()}
+	}, IndexNamespacedOnly())
+	if err == nil {
+		t.Error("expected an error indexing cached objects without a namespace")
+	}
+
+	list := &rbacv1.ClusterRoleList{}
+	if err := c.List(list, client.MatchingFields{"metadata.name": "admin"}); err != nil || len(list.Items) != 1 {
+		t.Errorf("failed to list the cluster role: items %d, err %v", len(list.Items), err)
+	}
+}
+
+func TestListFieldNotEquals(t *testing.T) {
+	c := newTestCache(t)
+	indexTier(t, c)
+	mustAdd(t, c,
+		withTier(deployment("a", "web", nil), "frontend"),
+		withTier(deployment("a", "db", nil), "backend"),
+		deployment("a", "job", nil),
+		withTier(deployment("b", "web", nil), "frontend"),
+		withTier(deployment("b", "api", nil), "backend"),
+	)
+
+	tests := []struct {
+		name     string
+		selector fields.Selector
+		opts     []client.ListOption
+		want     []string
+	}{
+		{
+			name:     "negated only",
+			selector: fields.OneTermNotEqualSelector("tier", "frontend"),
+			want:     []string{"a/db", "a/job", "b/api"},
+		},
+		{
+			name: "positive then negated",
+			selector: fields.AndSelectors(
+				fields.OneTermEqualSelector("metadata.namespace", "a"),
+				fields.OneTermNotEqualSelector("tier", "frontend"),
+			),
+			want: []string{"a/db", "a/job"},
+		},
+		{
+			name: "negated then positive",
+			selector: fields.AndSelectors(
+				fields.OneTermNotEqualSelector("tier", "frontend"),
+				fields.OneTermEqualSelector("metadata.name", "web"),
+			),
+			want: []string{},
+		},
+		{
+			name: "negated then positive in a namespace",
+			selector: fields.AndSelectors(
+				fields.OneTermNotEqualSelector("metadata.name", "db"),
+				fields.OneTermEqualSelector("tier", "backend"),
+			),
+			opts: []client.ListOption{client.InNamespace("b")},
+			want: []string{"b/api"},
+		},
+		{
+			name: "two negated",
+			selector: fields.AndSelectors(
+				fields.OneTermNotEqualSelector("tier", "frontend"),
+				fields.OneTermNotEqualSelector("metadata.name", "db"),
+			),
+			want: []string{"a/job", "b/api"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]client.ListOption{client.MatchingFieldsSelector{Selector: tt.selector}}, tt.opts...)
+			got := listDeploymentKeys(t, c, opts...)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectionGetsCopies(t *testing.T) {
+	c := newTestCache(t)
+	if err := c.IndexLabel(&appsv1.Deployment{}, "app"); err != nil {
+		t.Fatalf("failed to index the app label: %v", err)
+	}
+	mustAdd(t, c, deployment("default", "web", map[string]string{"app": "web"}))
+
+	var names []string
+	project := Projection(func(o client.Object) {
+		names = append(names, o.GetName())
+		o.SetLabels(map[string]string{"app": "projected"})
+	})
+	if err := c.List(&appsv1.DeploymentList{}, project); err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if want := []string{"web"}; !slices.Equal(names, want) {
+		t.Fatalf("projected %v, want %v", names, want)
+	}
+
+	item, exists, err := c.Get(deployment("default", "web", nil))
+	if err != nil || !exists {
+		t.Fatalf("failed to get the deployment: exists %v, err %v", exists, err)
+	}
+	if app := item.(*appsv1.Deployment).Labels["app"]; app != "web" {
+		t.Errorf("got app label %q, want %q", app, "web")
+	}
+	if keys := listDeploymentKeys(t, c, client.MatchingLabels{"app": "web"}); !slices.Equal(keys, []string{"default/web"}) {
+		t.Errorf("listed %v by the original label, want [default/web]", keys)
+	}
+	if keys := listDeploymentKeys(t, c, client.MatchingLabels{"app": "projected"}); len(keys) != 0 {
+		t.Errorf("listed %v by the projected label, want none", keys)
+	}
+}
+
+func TestListEmptyFieldSelector(t *testing.T) {
+	c := newTestCache(t)
+	mustAdd(t, c,
+		deployment("a", "web", nil),
+		deployment("b", "web", nil),
+		deployment("", "orphan", nil),
+	)
+
+	tests := []struct {
+		name string
+		opts []client.ListOption
+		want []string
+	}{
+		{
+			name: "empty matching fields",
+			opts: []client.ListOption{client.MatchingFields{}},
+			want: []string{"/orphan", "a/web", "b/web"},
+		},
+		{
+			name: "everything",
+			opts: []client.ListOption{client.MatchingFieldsSelector{Selector: fields.Everything()}},
+			want: []string{"/orphan", "a/web", "b/web"},
+		},
+		{
+			name: "everything in a namespace",
+			opts: []client.ListOption{client.MatchingFieldsSelector{Selector: fields.Everything()}, client.InNamespace("a")},
+			want: []string{"a/web"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := listDeploymentKeys(t, c, tt.opts...)
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListLabelAndFieldIndexes(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []client.ListOption
+		want []string
+	}{
+		{
+			name: "label and field",
+			opts: []client.ListOption{client.MatchingLabels{"app": "shop"}, client.MatchingFields{"tier": "frontend"}},
+			want: []string{"a/web", "b/web"},
+		},
+		{
+			name: "label and field in a namespace",
+			opts: []client.ListOption{client.MatchingLabels{"app": "shop"}, client.MatchingFields{"tier": "frontend"}, client.InNamespace("b")},
+			want: []string{"b/web"},
+		},
+		{
+			name: "label and field without common objects",
+			opts: []client.ListOption{client.MatchingLabels{"app": "blog"}, client.MatchingFields{"tier": "backend"}},
+			want: []string{},
+		},
+		{
+			name: "indexed and scanned labels with a field",
+			opts: []client.ListOption{client.MatchingLabels{"app": "shop", "env": "prod"}, client.MatchingFields{"tier": "frontend"}},
+			want: []string{"a/web"},
+		},
+		{
+			name: "label only",
+			opts: []client.ListOption{client.MatchingLabels{"app": "shop"}},
+			want: []string{"a/db", "a/web", "b/web"},
+		},
+	}
+	for _, labelIndexed := range []bool{false, true} {
+		c := newTestCache(t)
+		indexTier(t, c)
+		if labelIndexed {
+			if err := c.IndexLabel(&appsv1.Deployment{}, "app"); err != nil {
+				t.Fatalf("failed to index the app label: %v", err)
+			}
+		}
+		mustAdd(t, c,
+			withTier(deployment("a", "web", map[string]string{"app": "shop", "env": "prod"}), "frontend"),
+			withTier(deployment("a", "db", map[string]string{"app": "shop"}), "backend"),
+			withTier(deployment("b", "web", map[string]string{"app": "shop"}), "frontend"),
+			withTier(deployment("b", "blog", map[string]string{"app": "blog"}), "frontend"),
+		)
+
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/label indexed %v", tt.name, labelIndexed), func(t *testing.T) {
+				got := listDeploymentKeys(t, c, tt.opts...)
+				slices.Sort(got)
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkListLabelAndField compares listing by a label through a scan of the store, or of
+// the objects of a field index, to listing through a label index.
+func BenchmarkListLabelAndField(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		opts []client.ListOption
+	}{
+		{name: "label", opts: []client.ListOption{client.MatchingLabels{"app": "app-7"}}},
+		{name: "label and field", opts: []client.ListOption{client.MatchingLabels{"app": "app-7"}, client.MatchingFields{"tier": "tier-3"}}},
+	}
+	for _, labelIndexed := range []bool{false, true} {
+		c := newTestCache(b)
+		indexTier(b, c)
+		if labelIndexed {
+			if err := c.IndexLabel(&appsv1.Deployment{}, "app"); err != nil {
+				b.Fatalf("failed to index the app label: %v", err)
+			}
+		}
+		for i := 0; i < 10000; i++ {
+			obj := deployment(fmt.Sprintf("ns-%d", i%10), fmt.Sprintf("deploy-%d", i), map[string]string{"app": fmt.Sprintf("app-%d", i%100)})
+			mustAdd(b, c, withTier(obj, fmt.Sprintf("tier-%d", i%7)))
+		}
+
+		path := "scan"
+		if labelIndexed {
+			path = "indexed"
+		}
+		for _, bm := range benchmarks {
+			b.Run(bm.name+"/"+path, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if err := c.List(&appsv1.DeploymentList{}, bm.opts...); err != nil {
+						b.Fatalf("failed to list: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestListStandardOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []client.ListOption
+		want []string
+		// acrossNamespaces is set if the options select fields in every namespace, which
+		// namespaced only indexes do not support.
+		acrossNamespaces bool
+	}{
+		{
+			name: "namespace",
+			opts: []client.ListOption{client.InNamespace("a")},
+			want: []string{"a/blog", "a/db", "a/web"},
+		},
+		{
+			name: "labels",
+			opts: []client.ListOption{client.MatchingLabels{"app": "shop"}},
+			want: []string{"a/db", "a/web", "ab/web", "b/web"},
+		},
+		{
+			name:             "fields",
+			opts:             []client.ListOption{client.MatchingFields{"tier": "frontend"}},
+			want:             []string{"a/blog", "a/web", "ab/web", "b/web"},
+			acrossNamespaces: true,
+		},
+		{
+			name: "namespace and labels",
+			opts: []client.ListOption{client.InNamespace("a"), client.MatchingLabels{"app": "shop"}},
+			want: []string{"a/db", "a/web"},
+		},
+		{
+			name: "namespace and fields",
+			opts: []client.ListOption{client.InNamespace("a"), client.MatchingFields{"tier": "frontend"}},
+			want: []string{"a/blog", "a/web"},
+		},
+		{
+			name:             "labels and fields",
+			opts:             []client.ListOption{client.MatchingLabels{"app": "shop"}, client.MatchingFields{"tier": "frontend"}},
+			want:             []string{"a/web", "ab/web", "b/web"},
+			acrossNamespaces: true,
+		},
+		{
+			name: "namespace, labels and fields",
+			opts: []client.ListOption{client.InNamespace("b"), client.MatchingLabels{"app": "shop"}, client.MatchingFields{"tier": "frontend"}},
+			want: []string{"b/web"},
+		},
+		{
+			name: "namespace, labels and fields without matches",
+			opts: []client.ListOption{client.InNamespace("b"), client.MatchingLabels{"app": "blog"}, client.MatchingFields{"tier": "frontend"}},
+			want: []string{},
+		},
+	}
+	for _, labelIndexed := range []bool{false, true} {
+		for _, namespacedOnly := range []bool{false, true} {
+			c := newTestCache(t)
+			var indexOpts []IndexOption
+			if namespacedOnly {
+				indexOpts = append(indexOpts, IndexNamespacedOnly())
+			}
+			err := c.IndexField(&appsv1.Deployment{}, "tier", func(o client.Object) []string {
+				return []string{o.GetAnnotations()["tier"]}
+			}, indexOpts...)
+			if err != nil {
+				t.Fatalf("failed to index tiers: %v", err)
+			}
+			if labelIndexed {
+				if err := c.IndexLabel(&appsv1.Deployment{}, "app"); err != nil {
+					t.Fatalf("failed to index the app label: %v", err)
+				}
+			}
+			mustAdd(t, c,
+				withTier(deployment("a", "web", map[string]string{"app": "shop"}), "frontend"),
+				withTier(deployment("a", "db", map[string]string{"app": "shop"}), "backend"),
+				withTier(deployment("a", "blog", map[string]string{"app": "blog"}), "frontend"),
+				withTier(deployment("ab", "web", map[string]string{"app": "shop"}), "frontend"),
+				withTier(deployment("b", "web", map[string]string{"app": "shop"}), "frontend"),
+				withTier(deployment("b", "api", map[string]string{"app": "api"}), "backend"),
+			)
+
+			for _, tt := range tests {
+				name := fmt.Sprintf("%s/label indexed %v/namespaced only %v", tt.name, labelIndexed, namespacedOnly)
+				t.Run(name, func(t *testing.T) {
+					list := &appsv1.DeploymentList{}
+					err := c.List(list, tt.opts...)
+					if namespacedOnly && tt.acrossNamespaces {
+						if err == nil {
+							t.Error("expected an error listing a namespaced only index across namespaces")
+						}
+						return
+					}
+					if err != nil {
+						t.Fatalf("failed to list: %v", err)
+					}
+
+					got := make([]string, 0, len(list.Items))
+					for i := range list.Items {
+						got = append(got, client.ObjectKeyFromObject(&list.Items[i]).String())
+					}
+					slices.Sort(got)
+					if !slices.Equal(got, tt.want) {
+						t.Errorf("got %v, want %v", got, tt.want)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestUnregisterKindKeepsWaitForSyncWaiting(t *testing.T) {
+	c := newTestCache(t)
+	waited := make(chan bool)
+	go func() {
+		waited <- c.WaitForSync(context.Background(), &appsv1.Deployment{})
+	}()
+	// the deployments are unregistered once WaitForSync waits for their channel.
+	for {
+		c.mu.RLock()
+		_, waiting := c.synced[appsv1.SchemeGroupVersion.WithKind("Deployment")]
+		c.mu.RUnlock()
+		if waiting {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := c.UnregisterKind(&appsv1.Deployment{}); err != nil {
+		t.Fatalf("failed to unregister deployments: %v", err)
+	}
+	if err := c.RegisterKind(&appsv1.Deployment{}); err != nil {
+		t.Fatalf("failed to register deployments again: %v", err)
+	}
+	if err := c.MarkSynced(&appsv1.Deployment{}); err != nil {
+		t.Fatalf("failed to mark deployments as synced: %v", err)
+	}
+
+	select {
+	case synced := <-waited:
+		if !synced {
+			t.Error("WaitForSync returned false")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WaitForSync did not return once the GVK was marked as synced again")
+	}
+
+	if err := c.UnregisterKind(&appsv1.Deployment{}); err != nil {
+		t.Fatalf("failed to unregister deployments again: %v", err)
+	}
+	if c.HasSynced(&appsv1.Deployment{}) {
+		t.Error("deployments are still synced once unregistered")
+	}
+}
+
+func TestUnregisterKindStopsDispatchers(t *testing.T) {
+	c := newTestCache(t)
+	c.EnableAsyncDispatch()
+	mustAdd(t, c, deployment("default", "web", nil))
+	h := &recordingHandler{}
+	if err := c.AddEventHandler(&appsv1.Deployment{}, h); err != nil {
+		t.Fatalf("failed to add the event handler: %v", err)
+	}
+	c.dispatchMu.Lock()
+	dispatchers := slices.Clone(c.dispatchers)
+	c.dispatchMu.Unlock()
+
+	if err := c.UnregisterKind(&appsv1.Deployment{}); err != nil {
+		t.Fatalf("failed to unregister deployments: %v", err)
+	}
+	c.dispatchMu.Lock()
+	left := len(c.dispatchers)
+	c.dispatchMu.Unlock()
+	if left != 0 {
+		t.Errorf("got %d dispatchers left, want none", left)
+	}
+	for _, d := range dispatchers {
+		select {
+		case <-d.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the dispatcher of the unregistered handler did not stop")
+		}
+	}
+
+	want := []string{"add default/web", "delete default/web"}
+	if got := h.recorded(); !slices.Equal(got, want) {
+		t.Errorf("got events %v, want %v", got, want)
+	}
+}
+
+func TestUnregisterKindKeepsMaxSize(t *testing.T) {
+	c := newTestCache(t)
+	if err := c.SetMaxSize(&appsv1.Deployment{}, 1); err != nil {
+		t.Fatalf("failed to set the max size: %v", err)
+	}
+	mustAdd(t, c, deployment("default", "a", nil))
+	if err := c.UnregisterKind(&appsv1.Deployment{}); err != nil {
+		t.Fatalf("failed to unregister deployments: %v", err)
+	}
+
+	mustAdd(t, c, deployment("default", "b", nil), deployment("default", "c", nil))
+	if got, want := listDeploymentKeys(t, c), []string{"default/c"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestIndexLabelWhileListing(t *testing.T) {
+	c := newTestCache(t)
+	mustAdd(t, c, deployment("default", "web", map[string]string{"app": "web", "tier": "frontend"}))
+
+	var wg sync.WaitGroup
+	for _, key := range []string{"app", "tier", "env"} {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := c.IndexLabel(&appsv1.Deployment{}, key); err != nil {
+				t.Errorf("failed to index the %s label: %v", key, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := c.List(&appsv1.DeploymentList{}, client.MatchingLabels{key: "web"}); err != nil {
+				t.Errorf("failed to list by the %s label: %v", key, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got := listDeploymentKeys(t, c, client.MatchingLabels{"app": "web"})
+	if want := []string{"default/web"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// listDeploymentPage lists a page of the cached deployments and returns their keys with the
+// continue token of the next page.
+func listDeploymentPage(t testing.TB, c *CacheStores, opts ...client.ListOption) ([]string, string) {
+	t.Helper()
+
+	list := &appsv1.DeploymentList{}
+	if err := c.List(list, opts...); err != nil {
+		t.Fatalf("failed to list deployments: %v", err)
+	}
+
+	keys := make([]string, 0, len(list.Items))
+	for i := range list.Items {
+		keys = append(keys, client.ObjectKeyFromObject(&list.Items[i]).String())
+	}
+	return keys, list.Continue
+}
+
+func TestListContinue(t *testing.T) {
+	newCache := func(t *testing.T) *CacheStores {
+		c := newTestCache(t)
+		mustAdd(t, c,
+			deployment("a", "x", nil),
+			deployment("a", "y", nil),
+			deployment("b", "x", nil),
+			deployment("b", "y", nil),
+			deployment("c", "x", nil),
+		)
+		return c
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		c := newCache(t)
+		var got []string
+		token := ""
+		for pages := 0; ; pages++ {
+			if pages > 5 {
+				t.Fatalf("the list did not end after %d pages", pages)
+			}
+			keys, next := listDeploymentPage(t, c, client.Limit(2), client.Continue(token))
+			got = append(got, keys...)
+			if next == "" {
+				break
+			}
+			token = next
+		}
+		if want := []string{"a/x", "a/y", "b/x", "b/y", "c/x"}; !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("pages in a namespace", func(t *testing.T) {
+		c := newCache(t)
+		keys, token := listDeploymentPage(t, c, client.InNamespace("b"), client.Limit(1))
+		if want := []string{"b/x"}; !slices.Equal(keys, want) || token == "" {
+			t.Fatalf("got %v with token %q, want %v and a token", keys, token, want)
+		}
+		keys, token = listDeploymentPage(t, c, client.InNamespace("b"), client.Limit(1), client.Continue(token))
+		if want := []string{"b/y"}; !slices.Equal(keys, want) {
+			t.Errorf("got %v, want %v", keys, want)
+		}
+		if token != "" {
+			t.Errorf("got token %q after the last page, want none", token)
+		}
+	})
+
+	t.Run("token crossing namespaces", func(t *testing.T) {
+		c := newCache(t)
+		// the last object of the first page is the last one of its namespace.
+		_, token := listDeploymentPage(t, c, client.Limit(2))
+		keys, _ := listDeploymentPage(t, c, client.Limit(2), client.Continue(token))
+		if want := []string{"b/x", "b/y"}; !slices.Equal(keys, want) {
+			t.Errorf("got %v, want %v", keys, want)
+		}
+		// a token of an all namespaces list resumes a list of a later namespace from its start.
+		keys, _ = listDeploymentPage(t, c, client.InNamespace("c"), client.Continue(token))
+		if want := []string{"c/x"}; !slices.Equal(keys, want) {
+			t.Errorf("got %v in namespace c, want %v", keys, want)
+		}
+	})
+
+	t.Run("last object deleted between pages", func(t *testing.T) {
+		c := newCache(t)
+		_, token := listDeploymentPage(t, c, client.Limit(3))
+		if err := c.Delete(deployment("b", "x", nil)); err != nil {
+			t.Fatalf("failed to delete: %v", err)
+		}
+		keys, _ := listDeploymentPage(t, c, client.Limit(3), client.Continue(token))
+		if want := []string{"b/y", "c/x"}; !slices.Equal(keys, want) {
+			t.Errorf("got %v, want %v", keys, want)
+		}
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		c := newCache(t)
+		for _, token := range []string{"not base64!", encodeContinueToken("a/b/c")} {
+			if err := c.List(&appsv1.DeploymentList{}, client.Continue(token)); err == nil {
+				t.Errorf("expected an error listing with the token %q", token)
+			}
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Clone returns an independent copy of the cache: every registered GVK is registered again
+// with its key function and indexes, and every cached object is copied, so mutating either
+// cache does not affect the other. The settings of the cache, including its logger and
+// loader, are copied as well, but event handlers, the observer and the synced marks are not.
+func (s *CacheStores) Clone() (*CacheStores, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	clone := &CacheStores{
+		storesByGvk:     make(cacheStore, len(s.storesByGvk)),
+		keyFuncs:        maps.Clone(s.keyFuncs),
+		restMapper:      s.restMapper,
+		generations:     maps.Clone(s.generations),
+		resourceVersion: s.resourceVersion,
+		transform:       s.transform,
+		transforms:      maps.Clone(s.transforms),
+		conversion:      s.conversion,
+		aliases:         maps.Clone(s.aliases),
+		schemes:         s.schemeList(),
+		indexPrefix:     s.indexPrefix,
+	}
+	s.loadsMu.Lock()
+	clone.loader = s.loader
+	s.loadsMu.Unlock()
+	if logger := s.logger.Load(); logger != nil {
+		clone.logger.Store(logger)
+	}
+
+	for gvk, store := range s.storesByGvk {
+		items := store.List()
+		copies := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			obj, isObj := item.(runtime.Object)
+			if !isObj {
+				return nil, fmt.Errorf("cache contained %T, which is not an Object", item)
+			}
+			copies = append(copies, obj.DeepCopyObject())
+		}
+
+		// the index functions are stateless and shared, the indexed values are rebuilt.
+		newStore := cache.NewIndexer(s.keyFunc(gvk), maps.Clone(store.GetIndexers()))
+		if err := newStore.Replace(copies, ""); err != nil {
+			return nil, err
+		}
+		clone.storesByGvk[gvk] = newStore
+	}
+
+	if s.indexSpecs != nil {
+		clone.indexSpecs = make(map[schema.GroupVersionKind]map[string]string, len(s.indexSpecs))
+		for gvk, specs := range s.indexSpecs {
+			clone.indexSpecs[gvk] = maps.Clone(specs)
+		}
+	}
+	if s.namespacedOnly != nil {
+		clone.namespacedOnly = make(map[schema.GroupVersionKind]map[string]bool, len(s.namespacedOnly))
+		for gvk, indexes := range s.namespacedOnly {
+			clone.namespacedOnly[gvk] = maps.Clone(indexes)
+		}
+	}
+	clone.statusSubresources = maps.Clone(s.statusSubresources)
+	if s.statusVersions != nil {
+		clone.statusVersions = make(map[schema.GroupVersionKind]map[string]uint64, len(s.statusVersions))
+		for gvk, versions := range s.statusVersions {
+			clone.statusVersions[gvk] = maps.Clone(versions)
+		}
+	}
+	if s.composites != nil {
+		clone.composites = make(map[schema.GroupVersionKind][]compositeIndex, len(s.composites))
+		for gvk, idxs := range s.composites {
+			clone.composites[gvk] = slices.Clone(idxs)
+		}
+	}
+	if s.modified != nil {
+		clone.modified = make(map[schema.GroupVersionKind]map[string]time.Time, len(s.modified))
+		for gvk, modified := range s.modified {
+			clone.modified[gvk] = maps.Clone(modified)
+		}
+	}
+	if s.limits != nil {
+		clone.limits = make(map[schema.GroupVersionKind]*sizeLimit, len(s.limits))
+		for gvk, limit := range s.limits {
+			clone.limits[gvk] = &sizeLimit{
+				max:   limit.max,
+				order: slices.Clone(limit.order),
+				seqs:  maps.Clone(limit.seqs),
+				seq:   limit.seq,
+			}
+		}
+	}
+
+	return clone, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// compositeIndex is an index on the combined values of several fields.
+type compositeIndex struct {
+	name   string
+	fields []string
+}
+
+// CompositeKey joins the values of the fields of a composite index, in the order the fields
+// were given to IndexFields. Each value is prefixed with its length and a colon, e.g.
+// CompositeKey("a", "bc") is "1:a2:bc", so that values containing any character can not
+// make two sets of values share a key.
+func CompositeKey(values ...string) string {
+	var b strings.Builder
+	for _, v := range values {
+		b.WriteString(strconv.Itoa(len(v)))
+		b.WriteByte(':')
+		b.WriteString(v)
+	}
+	return b.String()
+}
+
+// IndexFields registers a composite index named indexName on the given fields for the GVK
+// of the given object. extract must return the keys built by CompositeKey from the values
+// of the fields, in the same order. List looks the objects up in the composite index
+// when its field selector holds exactly one `k=v` requirement on each of the fields.
+func (s *CacheStores) IndexFields(obj client.Object, indexName string, fields []string, extract client.IndexerFunc) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+	if extract == nil {
+		return fmt.Errorf("cannot register composite index %s with a nil function", indexName)
+	}
+	if len(fields) < 2 {
+		return fmt.Errorf("composite index %s requires at least two fields", indexName)
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		return ErrGvkNotFound
+	}
+
+	for _, idx := range s.composites[*gvk] {
+		if idx.name == indexName {
+			return fmt.Errorf("composite index with name %s already exists", indexName)
+		}
+	}
+
+	indexers := cache.Indexers{
+		compositeIdxName(indexName): s.indexFuncFor(*gvk, extract),
+	}
+	if err := s.checkIndexable(indexers, store.List()...); err != nil {
+		return err
+	}
+	if err := store.AddIndexers(indexers); err != nil {
+		return err
+	}
+
+	if s.composites == nil {
+		s.composites = make(map[schema.GroupVersionKind][]compositeIndex)
+	}
+	s.composites[*gvk] = append(s.composites[*gvk], compositeIndex{
+		name:   indexName,
+		fields: slices.Clone(fields),
+	})
+	s.logIndexRegistered(*gvk, compositeIdxName(indexName))
+
+	return nil
+}
+
+// compositeIndexRequirement returns the composite index and key to look up if the given
+// field requirements are exactly one `k=v` requirement on each field of a composite index
+// of the given GVK. The lock must be held by the caller.
+func (s *CacheStores) compositeIndexRequirement(gvk schema.GroupVersionKind, reqs fields.Requirements) (string, string, bool) {
+	values := make(map[string]string, len(reqs))
+	for _, req := range reqs {
+		if req.Operator != selection.Equals && req.Operator != selection.DoubleEquals {
+			return "", "", false
+		}
+		if _, dup := values[req.Field]; dup {
+			return "", "", false
+		}
+		values[req.Field] = req.Value
+	}
+
+	for _, idx := range s.composites[gvk] {
+		if len(idx.fields) != len(values) {
+			continue
+		}
+		ordered := make([]string, 0, len(idx.fields))
+		for _, field := range idx.fields {
+			v, ok := values[field]
+			if !ok {
+				break
+			}
+			ordered = append(ordered, v)
+		}
+		if len(ordered) == len(idx.fields) {
+			return compositeIdxName(idx.name), CompositeKey(ordered...), true
+		}
+	}
+
+	return "", "", false
+}
+
+func compositeIdxName(name string) string {
+	return compositeIdxPrefix + name
+}
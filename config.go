@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/go-logr/logr"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+)
+
+// Option configures a CacheStores created by New.
+type Option func(*CacheStores)
+
+// WithLogger sets the logger of the cache, see SetLogger.
+func WithLogger(logger logr.Logger) Option {
+	return func(s *CacheStores) {
+		s.SetLogger(logger)
+	}
+}
+
+// WithObserver sets the observer notified about the cache operations, see SetObserver.
+func WithObserver(o Observer) Option {
+	return func(s *CacheStores) {
+		s.SetObserver(o)
+	}
+}
+
+// WithIndexPrefix sets the prefix of the names of the client-go indexes backing the field
+// indexes of the cache, FieldIndexPrefix by default. It must be neither empty nor one of the
+// "label:" and "composite:" prefixes of the other indexes, or New fails.
+func WithIndexPrefix(prefix string) Option {
+	return func(s *CacheStores) {
+		s.indexPrefix = prefix
+	}
+}
+
+// WithRESTMapper sets the RESTMapper telling which GVKs are cluster-scoped, see
+// SetRESTMapper. Unlike SetRESTMapper, it applies to the stores created by New as well.
+func WithRESTMapper(mapper apimeta.RESTMapper) Option {
+	return func(s *CacheStores) {
+		s.restMapper = mapper
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// representation is the form in which an object, or the items of a list, are exposed.
+type representation int
+
+const (
+	// structured objects are the Go types registered in the scheme.
+	structured representation = iota
+	// unstructured objects are unstructured.Unstructured.
+	unstructuredObject
+	// partialMetadata objects are metav1.PartialObjectMetadata, holding only the metadata.
+	partialMetadata
+)
+
+// representationOf returns the representation of the given object or list.
+func representationOf(obj runtime.Object) representation {
+	switch obj.(type) {
+	case *metav1.PartialObjectMetadata, *metav1.PartialObjectMetadataList:
+		return partialMetadata
+	case runtime.Unstructured:
+		return unstructuredObject
+	default:
+		return structured
+	}
+}
+
+// convertObjects converts the given objects into the given representation. Structured objects
+// are created from the type registered in the scheme for gvk. Objects already in the requested
+// representation are returned as is.
+func convertObjects(objs []runtime.Object, to representation, scheme *runtime.Scheme, gvk schema.GroupVersionKind) ([]runtime.Object, error) {
+	for i, obj := range objs {
+		if representationOf(obj) == to {
+			continue
+		}
+
+		content, err := objectContent(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		var converted runtime.Object
+		switch to {
+		case unstructuredObject:
+			converted = &unstructured.Unstructured{Object: content}
+		case partialMetadata:
+			converted, err = contentToPartialMetadata(content)
+		default:
+			converted, err = contentToStructured(content, scheme, gvk)
+		}
+		if err != nil {
+			return nil, err
+		}
+		converted.GetObjectKind().SetGroupVersionKind(gvk)
+		objs[i] = converted
+	}
+
+	return objs, nil
+}
+
+// objectContent returns the unstructured content of the given object.
+func objectContent(obj runtime.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(runtime.Unstructured); ok {
+		return runtime.DeepCopyJSON(u.UnstructuredContent()), nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+func contentToStructured(content map[string]interface{}, scheme *runtime.Scheme, gvk schema.GroupVersionKind) (runtime.Object, error) {
+	obj, err := scheme.New(gvk)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert %s into a structured object: %w", gvk, err)
+	}
+
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func contentToPartialMetadata(content map[string]interface{}) (*metav1.PartialObjectMetadata, error) {
+	obj := &metav1.PartialObjectMetadata{}
+
+	metadata, _ := content["metadata"].(map[string]interface{})
+	if metadata == nil {
+		return obj, nil
+	}
+
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(metadata, &obj.ObjectMeta)
+	if err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// EnableConversion makes Get and List of a GVK without a store convert the objects of
+// another registered version of the same group and kind, using the conversions known to the
+// scheme. Objects are converted on every read, which is expensive. Versions the scheme has
+// no conversion for are ignored, and reads of the GVK fail with ErrGvkNotFound as they do
+// without conversion.
+func (s *CacheStores) EnableConversion() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conversion = true
+}
+
+// storeFor returns the store holding the objects of the given GVK and the GVK they are
+// stored as, which differs from the given one if they need to be converted. The lock must
+// be held by the caller.
+func (s *CacheStores) storeFor(gvk schema.GroupVersionKind) (cache.Indexer, schema.GroupVersionKind) {
+	if store := s.storesByGvk[gvk]; store != nil || !s.conversion || !s.schemeFor(gvk).Recognizes(gvk) {
+		return store, gvk
+	}
+
+	// pick the first convertible version so that the same store is always used. Versions
+	// the scheme has no conversion for are skipped, so that reads of gvk report it as not
+	// found rather than failing on every object.
+	var versions []schema.GroupVersionKind
+	for registered := range s.storesByGvk {
+		if registered.GroupKind() == gvk.GroupKind() && s.canConvert(registered, gvk) {
+			versions = append(versions, registered)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, gvk
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+
+	return s.storesByGvk[versions[0]], versions[0]
+}
+
+// canConvert reports whether the scheme of to converts the objects of from into to, by
+// converting an empty object.
+func (s *CacheStores) canConvert(from, to schema.GroupVersionKind) bool {
+	scheme := s.schemeFor(to)
+	in, err := scheme.New(from)
+	if err != nil {
+		return false
+	}
+	out, err := scheme.New(to)
+	if err != nil {
+		return false
+	}
+
+	return scheme.Convert(in, out, nil) == nil
+}
+
+// convertVersion returns a copy of the given object converted to the given GVK.
+func (s *CacheStores) convertVersion(obj runtime.Object, gvk schema.GroupVersionKind) (runtime.Object, error) {
+	scheme := s.schemeFor(gvk)
+	out, err := scheme.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := scheme.Convert(obj.DeepCopyObject(), out, nil); err != nil {
+		return nil, fmt.Errorf("cannot convert %s to %s: %w", obj.GetObjectKind().GroupVersionKind(), gvk, err)
+	}
+	out.GetObjectKind().SetGroupVersionKind(gvk)
+
+	return out, nil
+}
+
+// gvkNotFound returns the error reported when the given GVK has no store. The error wraps
+// ErrGvkNotFound and, if other versions of the same group and kind are registered, names
+// them to make the version skew obvious. The lock must be held by the caller.
+func (s *CacheStores) gvkNotFound(gvk schema.GroupVersionKind) error {
+	var versions []string
+	for registered := range s.storesByGvk {
+		if registered.GroupKind() == gvk.GroupKind() {
+			versions = append(versions, registered.String())
+		}
+	}
+	if len(versions) == 0 {
+		return ErrGvkNotFound
+	}
+	sort.Strings(versions)
+
+	return fmt.Errorf("%w: %s was requested, but the cache holds %s; see EnableConversion",
+		ErrGvkNotFound, gvk, strings.Join(versions, ", "))
+}
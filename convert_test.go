@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	"k8s.io/apimachinery/pkg/conversion"
+	util "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+// newConversionCache returns a cache with apps/v1 deployments registered, in which apps/v1beta1
+// deployments are known to the scheme and converted from apps/v1 if convertible is true.
+func newConversionCache(t *testing.T, convertible bool) *CacheStores {
+	t.Helper()
+
+	scheme := newTestScheme()
+	util.Must(appsv1beta1.AddToScheme(scheme))
+	if convertible {
+		util.Must(scheme.AddConversionFunc((*appsv1.Deployment)(nil), (*appsv1beta1.Deployment)(nil), func(a, b interface{}, _ conversion.Scope) error {
+			in, out := a.(*appsv1.Deployment), b.(*appsv1beta1.Deployment)
+			out.ObjectMeta = in.ObjectMeta
+			out.Spec.Replicas = in.Spec.Replicas
+			return nil
+		}))
+	}
+
+	c, err := New(scheme)
+	if err != nil {
+		t.Fatalf("failed to create the cache: %v", err)
+	}
+	if err := c.RegisterKind(&appsv1.Deployment{}); err != nil {
+		t.Fatalf("failed to register deployments: %v", err)
+	}
+	c.EnableConversion()
+
+	return c
+}
+
+func TestConversion(t *testing.T) {
+	c := newConversionCache(t, true)
+	replicas := int32(3)
+	obj := deployment("default", "web", nil)
+	obj.Spec.Replicas = &replicas
+	mustAdd(t, c, obj)
+
+	list := &appsv1beta1.DeploymentList{}
+	if err := c.List(list); err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "web" || *list.Items[0].Spec.Replicas != replicas {
+		t.Errorf("got %+v listed, want the converted web deployment", list.Items)
+	}
+
+	item, exists, err := c.Get(&appsv1beta1.Deployment{ObjectMeta: obj.ObjectMeta})
+	if err != nil || !exists {
+		t.Fatalf("failed to get: exists %v, err %v", exists, err)
+	}
+	got, ok := item.(*appsv1beta1.Deployment)
+	if !ok {
+		t.Fatalf("got %T, want *appsv1beta1.Deployment", item)
+	}
+	if *got.Spec.Replicas != replicas {
+		t.Errorf("got %d replicas, want %d", *got.Spec.Replicas, replicas)
+	}
+}
+
+func TestConversionWithoutConversionFunc(t *testing.T) {
+	c := newConversionCache(t, false)
+	mustAdd(t, c, deployment("default", "web", nil))
+
+	err := c.List(&appsv1beta1.DeploymentList{})
+	if !errors.Is(err, ErrGvkNotFound) {
+		t.Errorf("got error %v listing, want ErrGvkNotFound", err)
+	}
+	c.mu.RLock()
+	want := c.gvkNotFound(appsv1beta1.SchemeGroupVersion.WithKind("Deployment"))
+	c.mu.RUnlock()
+	if err == nil || err.Error() != want.Error() {
+		t.Errorf("got error %v listing, want %v", err, want)
+	}
+
+	_, _, err = c.Get(&appsv1beta1.Deployment{ObjectMeta: deployment("default", "web", nil).ObjectMeta})
+	if !errors.Is(err, ErrGvkNotFound) {
+		t.Errorf("got error %v getting, want ErrGvkNotFound", err)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type diffOptions struct {
+	ignoreResourceVersion bool
+}
+
+// DiffOption configures the comparison made by Diff.
+type DiffOption func(*diffOptions)
+
+// DiffIgnoreResourceVersion makes Diff consider objects differing only by their resource
+// version as unchanged.
+func DiffIgnoreResourceVersion() DiffOption {
+	return func(o *diffOptions) {
+		o.ignoreResourceVersion = true
+	}
+}
+
+// snapshot holds the objects of a cache by GVK, then by key.
+type snapshot map[schema.GroupVersionKind]map[string]runtime.Object
+
+// Diff compares two caches, typically a Clone taken earlier and the cache it was cloned
+// from, and returns copies of the objects only cached by newCache, of the objects of
+// newCache which differ from the ones of oldCache sharing their GVK and key, and of the
+// objects only cached by oldCache. Objects are ordered by GVK, then by key.
+func Diff(oldCache, newCache *CacheStores, opts ...DiffOption) (added, updated, deleted []client.Object, err error) {
+	if oldCache == nil || newCache == nil {
+		return nil, nil, nil, fmt.Errorf("cannot diff nil caches")
+	}
+
+	diffOpts := diffOptions{}
+	for _, opt := range opts {
+		opt(&diffOpts)
+	}
+
+	// each cache is locked in turn, so that diffing a cache with itself does not lock it twice.
+	oldObjs, err := oldCache.snapshot()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	newObjs, err := newCache.snapshot()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	gvks := make([]schema.GroupVersionKind, 0, len(oldObjs)+len(newObjs))
+	for gvk := range newObjs {
+		gvks = append(gvks, gvk)
+	}
+	for gvk := range oldObjs {
+		if _, ok := newObjs[gvk]; !ok {
+			gvks = append(gvks, gvk)
+		}
+	}
+	sortGVKs(gvks)
+
+	for _, gvk := range gvks {
+		for _, key := range sortedKeys(newObjs[gvk]) {
+			newObj := newObjs[gvk][key]
+			oldObj, existed := oldObjs[gvk][key]
+			if !existed {
+				added = append(added, copyObject(newObj))
+			} else if !objectsEqual(oldObj, newObj, diffOpts) {
+				updated = append(updated, copyObject(newObj))
+			}
+		}
+		for _, key := range sortedKeys(oldObjs[gvk]) {
+			if _, exists := newObjs[gvk][key]; !exists {
+				deleted = append(deleted, copyObject(oldObjs[gvk][key]))
+			}
+		}
+	}
+
+	return added, updated, deleted, nil
+}
+
+// snapshot returns the objects of the cache, without copying them.
+func (s *CacheStores) snapshot() (snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	objs := make(snapshot, len(s.storesByGvk))
+	for gvk, store := range s.storesByGvk {
+		byKey := make(map[string]runtime.Object)
+		for _, key := range store.ListKeys() {
+			item, exists, err := store.GetByKey(key)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				continue
+			}
+			obj, isObj := item.(client.Object)
+			if !isObj {
+				return nil, fmt.Errorf("cache contained %T, which is not a client.Object", item)
+			}
+			byKey[key] = obj
+		}
+		objs[gvk] = byKey
+	}
+
+	return objs, nil
+}
+
+func sortedKeys(objs map[string]runtime.Object) []string {
+	keys := make([]string, 0, len(objs))
+	for key := range objs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func objectsEqual(oldObj, newObj runtime.Object, opts diffOptions) bool {
+	if opts.ignoreResourceVersion {
+		oldCopy, newCopy := copyObject(oldObj), copyObject(newObj)
+		oldCopy.SetResourceVersion("")
+		newCopy.SetResourceVersion("")
+		return equality.Semantic.DeepEqual(oldCopy, newCopy)
+	}
+
+	return equality.Semantic.DeepEqual(oldObj, newObj)
+}
+
+// copyObject returns a deep copy of the given cached object, which snapshot made sure is a
+// client.Object.
+func copyObject(obj runtime.Object) client.Object {
+	return obj.DeepCopyObject().(client.Object)
+}
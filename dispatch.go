@@ -0,0 +1,248 @@
+package main
+
+import (
+	"slices"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// EnableAsyncDispatch makes the event handlers registered afterwards by AddEventHandler
+// be called from a goroutine of their own instead of synchronously by the mutating call.
+// Each handler receives the events in the order the mutations were applied to the store,
+// even when they are made concurrently. The queue of a handler is unbounded, so mutations
+// never block on a slow handler and no event is dropped, at the cost of the memory held by
+// the events it has yet to handle. Close stops the goroutines once their queues are drained.
+func (s *CacheStores) EnableAsyncDispatch() {
+	s.dispatchMu.Lock()
+	defer s.dispatchMu.Unlock()
+
+	s.asyncDispatch = true
+}
+
+// Close delivers the events queued for the handlers dispatched asynchronously, see
+// EnableAsyncDispatch, and stops their goroutines. The events of later mutations are
+// dropped for these handlers. The TTL reapers, see StartTTLReaper, are stopped as well.
+func (s *CacheStores) Close() error {
+	s.dispatchMu.Lock()
+	dispatchers := s.dispatchers
+	s.dispatchers = nil
+	s.asyncDispatch = false
+	s.dispatchMu.Unlock()
+
+	for _, d := range dispatchers {
+		d.close()
+	}
+	for _, d := range dispatchers {
+		<-d.done
+	}
+	s.stopReapers()
+
+	return nil
+}
+
+// stopDispatchers stops the dispatchers among the given handlers once the events already
+// queued are delivered, without waiting for them.
+func (s *CacheStores) stopDispatchers(handlers []cache.ResourceEventHandler) {
+	s.dispatchMu.Lock()
+	defer s.dispatchMu.Unlock()
+
+	for _, h := range handlers {
+		d, ok := h.(*dispatcher)
+		if !ok {
+			continue
+		}
+		d.close()
+		s.dispatchers = slices.DeleteFunc(s.dispatchers, func(other *dispatcher) bool {
+			return other == d
+		})
+	}
+}
+
+// asyncHandler returns the given handler wrapped by a dispatcher if asynchronous dispatch
+// is enabled, and the handler itself otherwise.
+func (s *CacheStores) asyncHandler(handler cache.ResourceEventHandler) cache.ResourceEventHandler {
+	s.dispatchMu.Lock()
+	defer s.dispatchMu.Unlock()
+
+	if !s.asyncDispatch {
+		return handler
+	}
+
+	d := &dispatcher{done: make(chan struct{})}
+	d.ready = sync.NewCond(&d.mu)
+	s.dispatchers = append(s.dispatchers, d)
+	go d.run(s, handler)
+
+	return d
+}
+
+// reserveHandlers returns the handlers of the given GVK to notify about a mutation, with
+// the dispatchers replaced by the batches they reserve for it, so that the events are
+// dispatched in the order the mutations were applied. The write lock must be held by the
+// caller, and the batches released by releaseHandlers once the events are notified.
+func (s *CacheStores) reserveHandlers(gvk schema.GroupVersionKind) []cache.ResourceEventHandler {
+	handlers := s.handlers[gvk]
+
+	var reserved []cache.ResourceEventHandler
+	for i, h := range handlers {
+		d, ok := h.(*dispatcher)
+		if !ok {
+			continue
+		}
+		if reserved == nil {
+			reserved = slices.Clone(handlers)
+		}
+		reserved[i] = d.reserve()
+	}
+	if reserved == nil {
+		return handlers
+	}
+
+	return reserved
+}
+
+// releaseHandlers releases the batches of the given handlers, see reserveHandlers.
+func releaseHandlers(handlers []cache.ResourceEventHandler) {
+	for _, h := range handlers {
+		if b, ok := h.(*eventBatch); ok {
+			b.release()
+		}
+	}
+}
+
+// dispatcher is a cache.ResourceEventHandler queueing the events of a handler in batches,
+// which are delivered in order by run.
+type dispatcher struct {
+	mu sync.Mutex
+	// ready is signaled when a batch is filled or released, or when the dispatcher is closed.
+	ready   *sync.Cond
+	batches []*eventBatch
+	closed  bool
+	done    chan struct{}
+}
+
+// eventBatch is the cache.ResourceEventHandler queueing the events of a single mutation
+// for a dispatcher. Batches are reserved while the write lock is held, which orders them,
+// and are delivered once released, after the events of the previous batches.
+type eventBatch struct {
+	// d is nil if the batch was reserved after the dispatcher was closed.
+	d        *dispatcher
+	events   []func(cache.ResourceEventHandler)
+	released bool
+}
+
+func (d *dispatcher) OnAdd(obj interface{}, isInInitialList bool) {
+	b := d.reserve()
+	defer b.release()
+	b.OnAdd(obj, isInInitialList)
+}
+
+func (d *dispatcher) OnUpdate(oldObj, newObj interface{}) {
+	b := d.reserve()
+	defer b.release()
+	b.OnUpdate(oldObj, newObj)
+}
+
+func (d *dispatcher) OnDelete(obj interface{}) {
+	b := d.reserve()
+	defer b.release()
+	b.OnDelete(obj)
+}
+
+// reserve queues a batch after the ones already reserved.
+func (d *dispatcher) reserve() *eventBatch {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return &eventBatch{}
+	}
+	b := &eventBatch{d: d}
+	d.batches = append(d.batches, b)
+
+	return b
+}
+
+func (b *eventBatch) OnAdd(obj interface{}, isInInitialList bool) {
+	b.enqueue(func(h cache.ResourceEventHandler) {
+		h.OnAdd(obj, isInInitialList)
+	})
+}
+
+func (b *eventBatch) OnUpdate(oldObj, newObj interface{}) {
+	b.enqueue(func(h cache.ResourceEventHandler) {
+		h.OnUpdate(oldObj, newObj)
+	})
+}
+
+func (b *eventBatch) OnDelete(obj interface{}) {
+	b.enqueue(func(h cache.ResourceEventHandler) {
+		h.OnDelete(obj)
+	})
+}
+
+func (b *eventBatch) enqueue(notify func(cache.ResourceEventHandler)) {
+	if b.d == nil {
+		return
+	}
+
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
+
+	b.events = append(b.events, notify)
+	b.d.ready.Signal()
+}
+
+// release lets run move past the batch once its events are delivered.
+func (b *eventBatch) release() {
+	if b.d == nil {
+		return
+	}
+
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
+
+	b.released = true
+	b.d.ready.Signal()
+}
+
+// run delivers the events of the batches in the order they were reserved, without waiting
+// for a batch to be released to deliver the events it already holds.
+func (d *dispatcher) run(s *CacheStores, handler cache.ResourceEventHandler) {
+	defer close(d.done)
+
+	handlers := []cache.ResourceEventHandler{handler}
+	for {
+		d.mu.Lock()
+		for len(d.batches) == 0 || (len(d.batches[0].events) == 0 && !d.batches[0].released) {
+			if d.closed && len(d.batches) == 0 {
+				d.mu.Unlock()
+				return
+			}
+			d.ready.Wait()
+		}
+		b := d.batches[0]
+		events := b.events
+		b.events = nil
+		if b.released {
+			d.batches[0] = nil
+			d.batches = d.batches[1:]
+		}
+		d.mu.Unlock()
+
+		for _, notify := range events {
+			s.notifyHandlers("async", handlers, notify)
+		}
+	}
+}
+
+// close makes run return once the batches already reserved are delivered.
+func (d *dispatcher) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+	d.ready.Signal()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// versionRecorder records the resource versions of the objects of the updates it receives.
+type versionRecorder struct {
+	mu      sync.Mutex
+	updates [][2]string
+}
+
+func (r *versionRecorder) OnAdd(interface{}, bool) {}
+func (r *versionRecorder) OnDelete(interface{})    {}
+
+func (r *versionRecorder) OnUpdate(oldObj, newObj interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.updates = append(r.updates, [2]string{
+		oldObj.(client.Object).GetResourceVersion(),
+		newObj.(client.Object).GetResourceVersion(),
+	})
+}
+
+// yieldingObserver sleeps on writes, which are observed between the release of the lock and
+// the notification of the event handlers.
+type yieldingObserver struct{}
+
+func (yieldingObserver) OnAdd(schema.GroupVersionKind) {
+	time.Sleep(time.Duration(rand.IntN(100)) * time.Microsecond)
+}
+
+func (yieldingObserver) OnDelete(schema.GroupVersionKind)                   {}
+func (yieldingObserver) OnGet(schema.GroupVersionKind, bool)                {}
+func (yieldingObserver) OnList(schema.GroupVersionKind, time.Duration, int) {}
+
+func TestAsyncDispatchKeepsConcurrentUpdatesInOrder(t *testing.T) {
+	const writers, updates = 8, 200
+
+	c := newTestCache(t, WithObserver(yieldingObserver{}))
+	c.EnableAsyncDispatch()
+	mustAdd(t, c, deployment("default", "web", nil))
+	recorder := &versionRecorder{}
+	if err := c.AddEventHandler(&appsv1.Deployment{}, recorder); err != nil {
+		t.Fatalf("failed to add the event handler: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < updates; j++ {
+				if err := c.Update(deployment("default", "web", nil)); err != nil {
+					t.Errorf("failed to update: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := c.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if len(recorder.updates) != writers*updates {
+		t.Fatalf("got %d updates, want %d", len(recorder.updates), writers*updates)
+	}
+	// each update replaces the object of the previous one.
+	for i := 1; i < len(recorder.updates); i++ {
+		prev, cur := recorder.updates[i-1], recorder.updates[i]
+		if cur[0] != prev[1] {
+			t.Fatalf("update %d replaced resource version %s, want %s", i, cur[0], prev[1])
+		}
+		if next, _ := strconv.Atoi(prev[1]); cur[1] != strconv.Itoa(next+1) {
+			t.Fatalf("update %d stored resource version %s after %s", i, cur[1], prev[1])
+		}
+	}
+}
+
+// blockedHandler records the adds it receives once unblock is closed.
+type blockedHandler struct {
+	recordingHandler
+	unblock chan struct{}
+}
+
+func (h *blockedHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	<-h.unblock
+	h.recordingHandler.OnAdd(obj, isInInitialList)
+}
+
+func TestAsyncDispatchDoesNotBlockOnSlowHandlers(t *testing.T) {
+	const adds = 100
+
+	c := newTestCache(t)
+	c.EnableAsyncDispatch()
+	h := &blockedHandler{unblock: make(chan struct{})}
+	if err := c.AddEventHandler(&appsv1.Deployment{}, h); err != nil {
+		t.Fatalf("failed to add the event handler: %v", err)
+	}
+
+	// the queue is unbounded, so the adds complete while the handler is blocked.
+	want := make([]string, 0, adds)
+	for i := 0; i < adds; i++ {
+		name := "web-" + strconv.Itoa(i)
+		mustAdd(t, c, deployment("default", name, nil))
+		want = append(want, "add default/"+name)
+	}
+
+	close(h.unblock)
+	if err := c.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	if got := h.recorded(); !slices.Equal(got, want) {
+		t.Errorf("got %d events %v, want the %d adds in order", len(got), got, adds)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Dump writes the whole cache as a single JSON document mapping each registered GVK,
+// formatted as "<group>/<version>/<kind>" (or "<version>/<kind>" for the core group), to the
+// list of its objects sorted by key. The document can be loaded back with Load.
+func (s *CacheStores) Dump(w io.Writer) error {
+	s.mu.RLock()
+	dump := make(map[string][]interface{}, len(s.storesByGvk))
+	for gvk, store := range s.storesByGvk {
+		objs := store.List()
+		if err := sortByName(objs); err != nil {
+			s.mu.RUnlock()
+			return err
+		}
+		dump[dumpKey(gvk)] = objs
+	}
+	s.mu.RUnlock()
+
+	// encoding/json sorts the map keys, which keeps dumps of the same state identical.
+	return json.NewEncoder(w).Encode(dump)
+}
+
+// Load adds the objects of a document written by Dump to the cache. Objects of GVKs
+// registered in the scheme are decoded into their Go type, the others as unstructured.
+func (s *CacheStores) Load(r io.Reader) error {
+	dump := make(map[string][]json.RawMessage)
+	if err := json.NewDecoder(r).Decode(&dump); err != nil {
+		return fmt.Errorf("failed to decode cache dump: %w", err)
+	}
+
+	keys := make([]string, 0, len(dump))
+	for key := range dump {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var objs []client.Object
+	for _, key := range keys {
+		gvk, err := parseDumpKey(key)
+		if err != nil {
+			return err
+		}
+
+		for _, raw := range dump[key] {
+			obj, err := s.newObject(gvk)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(raw, obj); err != nil {
+				return fmt.Errorf("failed to decode %s object: %w", key, err)
+			}
+			obj.GetObjectKind().SetGroupVersionKind(gvk)
+			objs = append(objs, obj)
+		}
+	}
+
+	return s.AddAll(objs...)
+}
+
+// newObject returns an empty object of the given GVK, unstructured if the scheme does not know it.
+func (s *CacheStores) newObject(gvk schema.GroupVersionKind) (client.Object, error) {
+	scheme := s.schemeFor(gvk)
+	if !scheme.Recognizes(gvk) {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		return u, nil
+	}
+
+	obj, err := scheme.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+
+	cObj, ok := obj.(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a client.Object", gvk)
+	}
+
+	return cObj, nil
+}
+
+func dumpKey(gvk schema.GroupVersionKind) string {
+	return gvk.GroupVersion().String() + "/" + gvk.Kind
+}
+
+func parseDumpKey(key string) (schema.GroupVersionKind, error) {
+	i := strings.LastIndex(key, "/")
+	if i <= 0 || i == len(key)-1 {
+		return schema.GroupVersionKind{}, fmt.Errorf("invalid GVK %q in cache dump", key)
+	}
+
+	gv, err := schema.ParseGroupVersion(key[:i])
+	if err != nil {
+		return schema.GroupVersionKind{}, fmt.Errorf("invalid GVK %q in cache dump: %w", key, err)
+	}
+
+	return gv.WithKind(key[i+1:]), nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AddEventHandler registers a handler notified about the changes of the objects
+// sharing the GVK of the given object. Handlers are called synchronously, in
+// registration order, once Add, Update or Delete has successfully mutated the store,
+// unless EnableAsyncDispatch was called.
+// Adding an object whose key already exists is reported through OnUpdate. A panicking
+// handler is recovered and logged, see SetLogger, and the next handlers are still called.
+//
+// Like informers do, the objects already cached are replayed through OnAdd with
+// isInInitialList set, see cache.ResourceEventHandlerDetailedFuncs, before AddEventHandler
+// returns. The events of concurrent mutations are held back until the replay is over, so
+// the handler never receives the deletion of an object before its replayed addition.
+func (s *CacheStores) AddEventHandler(obj client.Object, handler cache.ResourceEventHandler) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+	if handler == nil {
+		return fmt.Errorf("cannot add nil event handler")
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	// a dispatcher queues the events of concurrent mutations after the replayed batch, a
+	// synchronous handler is wrapped by a replayGate holding them back.
+	handler = s.asyncHandler(handler)
+	if _, async := handler.(*dispatcher); !async {
+		gate := &replayGate{handler: handler, replaying: true}
+		defer gate.open(s)
+		handler = gate
+	}
+	existing, replay := s.addEventHandler(*gvk, handler)
+	defer releaseHandlers(replay)
+	if err := sortByName(existing); err != nil {
+		return err
+	}
+	for _, obj := range existing {
+		s.notifyHandlers("add", replay, func(h cache.ResourceEventHandler) {
+			h.OnAdd(obj, true)
+		})
+	}
+
+	return nil
+}
+
+// replayGate is the cache.ResourceEventHandler registered by AddEventHandler for a
+// synchronous handler, which holds back its events until the cached objects are replayed.
+type replayGate struct {
+	handler cache.ResourceEventHandler
+
+	mu        sync.Mutex
+	replaying bool
+	pending   []heldEvent
+}
+
+// heldEvent is an event held back by a replayGate.
+type heldEvent struct {
+	event  string
+	notify func(cache.ResourceEventHandler)
+}
+
+func (g *replayGate) OnAdd(obj interface{}, isInInitialList bool) {
+	g.deliver("add", func(h cache.ResourceEventHandler) {
+		h.OnAdd(obj, isInInitialList)
+	})
+}
+
+func (g *replayGate) OnUpdate(oldObj, newObj interface{}) {
+	g.deliver("update", func(h cache.ResourceEventHandler) {
+		h.OnUpdate(oldObj, newObj)
+	})
+}
+
+func (g *replayGate) OnDelete(obj interface{}) {
+	g.deliver("delete", func(h cache.ResourceEventHandler) {
+		h.OnDelete(obj)
+	})
+}
+
+func (g *replayGate) deliver(event string, notify func(cache.ResourceEventHandler)) {
+	g.mu.Lock()
+	if g.replaying {
+		g.pending = append(g.pending, heldEvent{event: event, notify: notify})
+		g.mu.Unlock()
+		return
+	}
+	g.mu.Unlock()
+
+	notify(g.handler)
+}
+
+// open delivers the events held back during the replay, including the ones arriving
+// meanwhile, then lets the next events through.
+func (g *replayGate) open(s *CacheStores) {
+	for {
+		g.mu.Lock()
+		pending := g.pending
+		g.pending = nil
+		if len(pending) == 0 {
+			g.replaying = false
+			g.mu.Unlock()
+			return
+		}
+		g.mu.Unlock()
+
+		for _, e := range pending {
+			s.notifyHandlers(e.event, []cache.ResourceEventHandler{g.handler}, e.notify)
+		}
+	}
+}
+
+// addEventHandler registers the given handler for the given GVK, and returns the objects
+// cached at that time with the handlers to replay them to, which must be released by
+// releaseHandlers once notified.
+func (s *CacheStores) addEventHandler(gvk schema.GroupVersionKind, handler cache.ResourceEventHandler) ([]interface{}, []cache.ResourceEventHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.handlers == nil {
+		s.handlers = make(map[schema.GroupVersionKind][]cache.ResourceEventHandler)
+	}
+	s.handlers[gvk] = append(s.handlers[gvk], handler)
+
+	replay := []cache.ResourceEventHandler{handler}
+	switch h := handler.(type) {
+	case *dispatcher:
+		replay[0] = h.reserve()
+	case *replayGate:
+		replay[0] = h.handler
+	}
+
+	if store := s.storesByGvk[gvk]; store != nil {
+		return store.List(), replay
+	}
+	return nil, replay
+}
+
+// addEvent is a notification about an added object, delivered once the lock is released.
+type addEvent struct {
+	gvk      schema.GroupVersionKind
+	handlers []cache.ResourceEventHandler
+	old      interface{}
+	obj      interface{}
+	exists   bool
+}
+
+func (s *CacheStores) notifyAdd(handlers []cache.ResourceEventHandler, obj interface{}) {
+	s.notifyHandlers("add", handlers, func(h cache.ResourceEventHandler) {
+		h.OnAdd(obj, false)
+	})
+}
+
+func (s *CacheStores) notifyUpdate(handlers []cache.ResourceEventHandler, oldObj, newObj interface{}) {
+	s.notifyHandlers("update", handlers, func(h cache.ResourceEventHandler) {
+		h.OnUpdate(oldObj, newObj)
+	})
+}
+
+func (s *CacheStores) notifyDelete(handlers []cache.ResourceEventHandler, obj interface{}) {
+	s.notifyHandlers("delete", handlers, func(h cache.ResourceEventHandler) {
+		h.OnDelete(obj)
+	})
+}
+
+// DeleteFromEvent deletes the object received by the OnDelete of an informer event
+// handler, which may be a cache.DeletedFinalStateUnknown tombstone when the informer missed
+// the deletion, in which case the last known state of the object it holds is deleted.
+func (s *CacheStores) DeleteFromEvent(obj interface{}) error {
+	switch tombstone := obj.(type) {
+	case cache.DeletedFinalStateUnknown:
+		obj = tombstone.Obj
+	case *cache.DeletedFinalStateUnknown:
+		if tombstone == nil {
+			return ErrNilObj
+		}
+		obj = tombstone.Obj
+	}
+	if obj == nil {
+		return ErrNilObj
+	}
+
+	clientObj, ok := obj.(client.Object)
+	if !ok {
+		return fmt.Errorf("cannot delete %T, which is not a client.Object", obj)
+	}
+
+	return s.Delete(clientObj)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// recordingHandler records the events it receives as "<event> <namespace/name>". If
+// replaying is set, the replayed additions signal it and wait for proceed before being
+// recorded.
+type recordingHandler struct {
+	mu     sync.Mutex
+	events []string
+
+	replaying chan struct{}
+	proceed   chan struct{}
+}
+
+func (h *recordingHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	if isInInitialList && h.replaying != nil {
+		close(h.replaying)
+		<-h.proceed
+	}
+	h.record("add", obj)
+}
+
+func (h *recordingHandler) OnUpdate(_, newObj interface{}) {
+	h.record("update", newObj)
+}
+
+func (h *recordingHandler) OnDelete(obj interface{}) {
+	h.record("delete", obj)
+}
+
+func (h *recordingHandler) record(event string, obj interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, event+" "+client.ObjectKeyFromObject(obj.(client.Object)).String())
+}
+
+func (h *recordingHandler) recorded() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.events)
+}
+
+func TestAddEventHandlerReplaysBeforeLiveEvents(t *testing.T) {
+	for _, async := range []bool{false, true} {
+		name := "sync"
+		if async {
+			name = "async"
+		}
+		t.Run(name, func(t *testing.T) {
+			c := newTestCache(t)
+			if async {
+				c.EnableAsyncDispatch()
+			}
+			mustAdd(t, c, deployment("default", "web", nil))
+
+			h := &recordingHandler{replaying: make(chan struct{}), proceed: make(chan struct{})}
+			added := make(chan error)
+			go func() {
+				added <- c.AddEventHandler(&appsv1.Deployment{}, h)
+			}()
+
+			// the deletion happens while the replay of the deleted object is in progress.
+			<-h.replaying
+			if err := c.Delete(deployment("default", "web", nil)); err != nil {
+				t.Fatalf("failed to delete: %v", err)
+			}
+			close(h.proceed)
+			if err := <-added; err != nil {
+				t.Fatalf("failed to add the event handler: %v", err)
+			}
+			if err := c.Close(); err != nil {
+				t.Fatalf("failed to close: %v", err)
+			}
+
+			want := []string{"add default/web", "delete default/web"}
+			if got := h.recorded(); !slices.Equal(got, want) {
+				t.Errorf("got events %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDeleteFromEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   func(obj client.Object) interface{}
+		wantErr bool
+	}{
+		{name: "object", event: func(obj client.Object) interface{} {
+			return obj
+		}},
+		{name: "tombstone", event: func(obj client.Object) interface{} {
+			return cache.DeletedFinalStateUnknown{Key: "default/web", Obj: obj}
+		}},
+		{name: "tombstone pointer", event: func(obj client.Object) interface{} {
+			return &cache.DeletedFinalStateUnknown{Key: "default/web", Obj: obj}
+		}},
+		{name: "nil tombstone pointer", wantErr: true, event: func(client.Object) interface{} {
+			return (*cache.DeletedFinalStateUnknown)(nil)
+		}},
+		{name: "empty tombstone", wantErr: true, event: func(client.Object) interface{} {
+			return cache.DeletedFinalStateUnknown{Key: "default/web"}
+		}},
+		{name: "tombstone of another type", wantErr: true, event: func(client.Object) interface{} {
+			return cache.DeletedFinalStateUnknown{Key: "default/web", Obj: "web"}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(t)
+			mustAdd(t, c, deployment("default", "web", nil))
+			h := &recordingHandler{}
+			if err := c.AddEventHandler(&appsv1.Deployment{}, h); err != nil {
+				t.Fatalf("failed to add the event handler: %v", err)
+			}
+
+			err := c.DeleteFromEvent(tt.event(deployment("default", "web", nil)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			_, exists, err := c.Get(deployment("default", "web", nil))
+			if err != nil {
+				t.Fatalf("failed to get: %v", err)
+			}
+			if exists != tt.wantErr {
+				t.Errorf("got exists %v after the deletion, want %v", exists, tt.wantErr)
+			}
+			want := []string{"add default/web", "delete default/web"}
+			if tt.wantErr {
+				want = want[:1]
+			}
+			if got := h.recorded(); !slices.Equal(got, want) {
+				t.Errorf("got events %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestReplaceWithDuplicateKeys(t *testing.T) {
+	gvk := appsv1.SchemeGroupVersion.WithKind("Deployment")
+	tests := []struct {
+		name       string
+		cached     []client.Object
+		wantEvents []string
+	}{
+		{name: "new key", wantEvents: []string{"add default/web"}},
+		{
+			name:       "cached key",
+			cached:     []client.Object{deployment("default", "web", nil)},
+			wantEvents: []string{"add default/web", "update default/web"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(t)
+			mustAdd(t, c, tt.cached...)
+			h := &recordingHandler{}
+			if err := c.AddEventHandler(&appsv1.Deployment{}, h); err != nil {
+				t.Fatalf("failed to add the event handler: %v", err)
+			}
+
+			err := c.Replace(gvk, []client.Object{
+				deployment("default", "web", map[string]string{"app": "first"}),
+				deployment("default", "web", map[string]string{"app": "last"}),
+			}, "")
+			if err != nil {
+				t.Fatalf("failed to replace: %v", err)
+			}
+
+			if got := h.recorded(); !slices.Equal(got, tt.wantEvents) {
+				t.Errorf("got events %v, want %v", got, tt.wantEvents)
+			}
+			n, err := c.Count(&appsv1.Deployment{})
+			if err != nil || n != 1 {
+				t.Errorf("got %d deployments, err %v, want 1", n, err)
+			}
+			item, exists, err := c.Get(deployment("default", "web", nil))
+			if err != nil || !exists {
+				t.Fatalf("failed to get: exists %v, err %v", exists, err)
+			}
+			if app := item.(*appsv1.Deployment).Labels["app"]; app != "last" {
+				t.Errorf("got app label %q, want %q", app, "last")
+			}
+		})
+	}
+}
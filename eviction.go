@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// sizeLimit tracks the insertion order of the objects of a GVK capped by SetMaxSize.
+type sizeLimit struct {
+	max int
+	// order holds the keys in insertion order. Entries whose sequence number no longer
+	// matches seqs are stale, i.e. their object was deleted or re-added since.
+	order []insertion
+	seqs  map[string]uint64
+	seq   uint64
+}
+
+type insertion struct {
+	key string
+	seq uint64
+}
+
+func (l *sizeLimit) track(key string) {
+	l.seq++
+	l.seqs[key] = l.seq
+	l.order = append(l.order, insertion{key: key, seq: l.seq})
+}
+
+func (l *sizeLimit) untrack(key string) {
+	delete(l.seqs, key)
+	// drop the stale entries once they outnumber the tracked ones.
+	if len(l.order) > 2*len(l.seqs)+16 {
+		live := make([]insertion, 0, len(l.seqs))
+		for _, entry := range l.order {
+			if seq, ok := l.seqs[entry.key]; ok && seq == entry.seq {
+				live = append(live, entry)
+			}
+		}
+		l.order = live
+	}
+}
+
+// SetMaxSize caps the number of objects cached for the GVK of the given object. Once the
+// cap is exceeded, the objects added first are evicted and event handlers are notified
+// through OnDelete. Eviction is best-effort: it follows insertion order, not accesses,
+// and the objects cached when the cap is set are ordered by creation timestamp. A max of
+// zero or less removes the cap.
+func (s *CacheStores) SetMaxSize(obj client.Object, max int) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if max <= 0 {
+		delete(s.limits, *gvk)
+		s.mu.Unlock()
+		return nil
+	}
+
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		store = s.registerGvkIntoCache(*gvk, cache.MetaNamespaceKeyFunc)
+	}
+
+	limit := &sizeLimit{max: max, seqs: make(map[string]uint64)}
+	if err := s.trackExisting(*gvk, store, limit); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	if s.limits == nil {
+		s.limits = make(map[schema.GroupVersionKind]*sizeLimit)
+	}
+	s.limits[*gvk] = limit
+
+	evicted, err := s.evict(*gvk, store)
+	handlers := s.reserveHandlers(*gvk)
+	defer releaseHandlers(handlers)
+	s.mu.Unlock()
+
+	s.notifyEvicted(*gvk, handlers, evicted)
+
+	return err
+}
+
+// trackExisting records the objects of the given store in limit, ordered by creation
+// timestamp. The write lock must be held by the caller.
+func (s *CacheStores) trackExisting(gvk schema.GroupVersionKind, store cache.Indexer, limit *sizeLimit) error {
+	objs := store.List()
+	if err := sortByName(objs); err != nil {
+		return err
+	}
+
+	keyFunc := s.keyFunc(gvk)
+	keys := make([]string, 0, len(objs))
+	created := make(map[string]int64, len(objs))
+	for _, obj := range objs {
+		clientObj, ok := obj.(client.Object)
+		if !ok {
+			return fmt.Errorf("cache contained %T, which is not a client.Object", obj)
+		}
+		key, err := keyFunc(obj)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		created[key] = clientObj.GetCreationTimestamp().UnixNano()
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return created[keys[i]] < created[keys[j]]
+	})
+	for _, key := range keys {
+		limit.track(key)
+	}
+
+	return nil
+}
+
+// trackAdded records a newly added object of the given GVK if it is capped, and returns
+// the objects evicted to stay within the cap. The write lock must be held by the caller.
+func (s *CacheStores) trackAdded(gvk schema.GroupVersionKind, store cache.Indexer, obj interface{}) ([]interface{}, error) {
+	limit := s.limits[gvk]
+	if limit == nil {
+		return nil, nil
+	}
+
+	key, err := s.keyFunc(gvk)(obj)
+	if err != nil {
+		return nil, err
+	}
+	limit.track(key)
+
+	return s.evict(gvk, store)
+}
+
+// evict removes the oldest objects of the given GVK until its cap is honored, and returns
+// them. The write lock must be held by the caller.
+func (s *CacheStores) evict(gvk schema.GroupVersionKind, store cache.Indexer) ([]interface{}, error) {
+	limit := s.limits[gvk]
+	if limit == nil {
+		return nil, nil
+	}
+
+	var evicted []interface{}
+	for len(limit.seqs) > limit.max && len(limit.order) > 0 {
+		oldest := limit.order[0]
+		limit.order = limit.order[1:]
+		if seq, ok := limit.seqs[oldest.key]; !ok || seq != oldest.seq {
+			continue
+		}
+		delete(limit.seqs, oldest.key)
+
+		obj, exists, err := store.GetByKey(oldest.key)
+		if err != nil {
+			return evicted, err
+		}
+		if !exists {
+			continue
+		}
+		if err := store.Delete(obj); err != nil {
+			return evicted, err
+		}
+		delete(s.modified[gvk], oldest.key)
+		delete(s.statusVersions[gvk], oldest.key)
+		evicted = append(evicted, obj)
+	}
+	if len(evicted) > 0 {
+		s.bumpGeneration(gvk)
+	}
+
+	return evicted, nil
+}
+
+// notifyEvicted notifies the observer and the event handlers about evicted objects.
+func (s *CacheStores) notifyEvicted(gvk schema.GroupVersionKind, handlers []cache.ResourceEventHandler, evicted []interface{}) {
+	observer := s.observe()
+	logger := s.log().V(1)
+	for _, obj := range evicted {
+		if meta, err := apimeta.Accessor(obj); err == nil {
+			logger.Info("evicted object", "gvk", gvk.String(), "namespace", meta.GetNamespace(), "name", meta.GetName())
+		}
+		observer.OnDelete(gvk)
+		s.notifyDelete(handlers, obj)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMaxSizeEvictsInInsertionOrder(t *testing.T) {
+	observer := &countingObserver{}
+	c := newTestCache(t, WithObserver(observer))
+	if err := c.SetMaxSize(&appsv1.Deployment{}, 2); err != nil {
+		t.Fatalf("failed to set the max size: %v", err)
+	}
+	h := &recordingHandler{}
+	if err := c.AddEventHandler(&appsv1.Deployment{}, h); err != nil {
+		t.Fatalf("failed to add the event handler: %v", err)
+	}
+
+	mustAdd(t, c, deployment("default", "a", nil), deployment("default", "b", nil))
+	// updates do not refresh the position of an object.
+	if err := c.Update(deployment("default", "a", map[string]string{"app": "a"})); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	mustAdd(t, c, deployment("default", "c", nil))
+
+	if got, want := listDeploymentKeys(t, c), []string{"default/b", "default/c"}; !slices.Equal(got, want) {
+		t.Errorf("got %v cached, want %v", got, want)
+	}
+	want := []string{"add default/a", "add default/b", "update default/a", "add default/c", "delete default/a"}
+	if got := h.recorded(); !slices.Equal(got, want) {
+		t.Errorf("got events %v, want %v", got, want)
+	}
+	if observer.deletes != 1 {
+		t.Errorf("got %d deletions observed, want 1", observer.deletes)
+	}
+
+	mustAdd(t, c, deployment("default", "d", nil))
+	if got, want := listDeploymentKeys(t, c), []string{"default/c", "default/d"}; !slices.Equal(got, want) {
+		t.Errorf("got %v cached, want %v", got, want)
+	}
+}
+
+func TestMaxSizeDeletionsFreeRoom(t *testing.T) {
+	c := newTestCache(t)
+	if err := c.SetMaxSize(&appsv1.Deployment{}, 2); err != nil {
+		t.Fatalf("failed to set the max size: %v", err)
+	}
+	mustAdd(t, c, deployment("default", "a", nil), deployment("default", "b", nil))
+	if err := c.Delete(deployment("default", "a", nil)); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	mustAdd(t, c, deployment("default", "c", nil))
+
+	if got, want := listDeploymentKeys(t, c), []string{"default/b", "default/c"}; !slices.Equal(got, want) {
+		t.Errorf("got %v cached, want %v", got, want)
+	}
+}
+
+func TestMaxSizeOfCachedObjects(t *testing.T) {
+	c := newTestCache(t)
+	h := &recordingHandler{}
+	if err := c.AddEventHandler(&appsv1.Deployment{}, h); err != nil {
+		t.Fatalf("failed to add the event handler: %v", err)
+	}
+	// the objects cached when the cap is set are evicted by creation timestamp.
+	now := time.Now()
+	for i, name := range []string{"c", "a", "b"} {
+		obj := deployment("default", name, nil)
+		obj.CreationTimestamp = metav1.NewTime(now.Add(time.Duration(i) * time.Minute))
+		mustAdd(t, c, obj)
+	}
+
+	if err := c.SetMaxSize(&appsv1.Deployment{}, 1); err != nil {
+		t.Fatalf("failed to set the max size: %v", err)
+	}
+	if got, want := listDeploymentKeys(t, c), []string{"default/b"}; !slices.Equal(got, want) {
+		t.Errorf("got %v cached, want %v", got, want)
+	}
+	want := []string{"add default/c", "add default/a", "add default/b", "delete default/c", "delete default/a"}
+	if got := h.recorded(); !slices.Equal(got, want) {
+		t.Errorf("got events %v, want %v", got, want)
+	}
+
+	// a max of zero removes the cap.
+	if err := c.SetMaxSize(&appsv1.Deployment{}, 0); err != nil {
+		t.Fatalf("failed to remove the max size: %v", err)
+	}
+	mustAdd(t, c, deployment("default", "d", nil), deployment("default", "e", nil))
+	if got, want := listDeploymentKeys(t, c), []string{"default/b", "default/d", "default/e"}; !slices.Equal(got, want) {
+		t.Errorf("got %v cached, want %v", got, want)
+	}
+}
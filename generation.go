@@ -0,0 +1,39 @@
+package main
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Generation returns a counter incremented by every mutation of the objects sharing the GVK
+// of the given object. Pollers can compare it between two checks to skip work when the
+// objects did not change. It returns ErrGvkNotFound if the GVK is not registered.
+func (s *CacheStores) Generation(obj client.Object) (uint64, error) {
+	if obj == nil {
+		return 0, ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.storesByGvk[*gvk] == nil {
+		return 0, ErrGvkNotFound
+	}
+
+	return s.generations[*gvk], nil
+}
+
+// bumpGeneration increments the generation of the given GVK, and the resource version of
+// the cache. The write lock must be held by the caller.
+func (s *CacheStores) bumpGeneration(gvk schema.GroupVersionKind) {
+	if s.generations == nil {
+		s.generations = make(map[schema.GroupVersionKind]uint64)
+	}
+	s.generations[gvk]++
+	s.resourceVersion++
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ownerUIDField is the field under which IndexByOwner indexes objects.
+const ownerUIDField = "metadata.ownerReferences.uid"
+
+const (
+	nameField      = "metadata.name"
+	namespaceField = "metadata.namespace"
+)
+
+// implicitFieldIndexers returns the field indexes every store of the given GVK is registered
+// with, so that the field selectors most commonly sent by Kubernetes clients work out of
+// the box.
+func (s *CacheStores) implicitFieldIndexers(gvk schema.GroupVersionKind) cache.Indexers {
+	return cache.Indexers{
+		s.fieldIdxName(nameField): s.indexFuncFor(gvk, func(o client.Object) []string {
+			return []string{o.GetName()}
+		}),
+		s.fieldIdxName(namespaceField): s.indexFuncFor(gvk, func(o client.Object) []string {
+			return []string{o.GetNamespace()}
+		}),
+	}
+}
+
+// isImplicitField reports whether the given field is indexed on every store.
+func isImplicitField(field string) bool {
+	return field == nameField || field == namespaceField
+}
+
+// IndexByOwner registers a field index on the UIDs of the owners of the objects sharing
+// the GVK of the given object. Objects with several owners are indexed under each UID.
+func (s *CacheStores) IndexByOwner(obj client.Object) error {
+	return s.indexField(obj, ownerUIDField, func(o client.Object) []string {
+		refs := o.GetOwnerReferences()
+		if len(refs) == 0 {
+			return nil
+		}
+
+		uids := make([]string, 0, len(refs))
+		for _, ref := range refs {
+			uids = append(uids, string(ref.UID))
+		}
+		return uids
+	}, "owner")
+}
+
+// ListByOwner lists the objects owned by the given owner UID using the index registered
+// by IndexByOwner.
+func (s *CacheStores) ListByOwner(list client.ObjectList, ownerUID types.UID, opts ...client.ListOption) error {
+	opts = append(opts, client.MatchingFields{ownerUIDField: string(ownerUID)})
+	return s.List(list, opts...)
+}
+
+// annotationField returns the field under which IndexByAnnotation indexes the given annotation.
+func annotationField(annotationKey string) string {
+	return "metadata.annotations." + annotationKey
+}
+
+// IndexByAnnotation registers a field index on the values of the given annotation for the
+// GVK of the given object. Objects without the annotation are not indexed.
+func (s *CacheStores) IndexByAnnotation(obj client.Object, annotationKey string) error {
+	return s.indexField(obj, annotationField(annotationKey), func(o client.Object) []string {
+		val, ok := o.GetAnnotations()[annotationKey]
+		if !ok {
+			return nil
+		}
+		return []string{val}
+	}, "annotation")
+}
+
+// ListByAnnotation lists the objects whose given annotation has the given value using the
+// index registered by IndexByAnnotation.
+func (s *CacheStores) ListByAnnotation(list client.ObjectList, annotationKey, value string, opts ...client.ListOption) error {
+	opts = append(opts, client.MatchingFields{annotationField(annotationKey): value})
+	return s.List(list, opts...)
+}
+
+// IndexByJSONPath registers a field index named indexName on the values found at the given
+// JSONPath, e.g. ".spec.template.metadata.labels.app", in the objects sharing the GVK of
+// the given object. The path is evaluated against the unstructured content of the objects;
+// objects missing it are not indexed. Values which are not strings are indexed as JSON.
+// Indexing indexName again with the same path is a no-op, whereas indexing it with another
+// path returns an error wrapping ErrIndexExists.
+func (s *CacheStores) IndexByJSONPath(obj client.Object, indexName, jsonPath string) error {
+	template := jsonPath
+	if !strings.HasPrefix(template, "{") {
+		template = "{" + template + "}"
+	}
+
+	parser := jsonpath.New(indexName).AllowMissingKeys(true)
+	if err := parser.Parse(template); err != nil {
+		return fmt.Errorf("invalid JSONPath %q: %w", jsonPath, err)
+	}
+
+	// the parser keeps state while evaluating a path, and index functions may be
+	// called concurrently by readers.
+	var mu sync.Mutex
+
+	return s.indexField(obj, indexName, func(o client.Object) []string {
+		content, err := objectContent(o)
+		if err != nil {
+			return nil
+		}
+
+		mu.Lock()
+		results, err := parser.FindResults(content)
+		mu.Unlock()
+		if err != nil {
+			return nil
+		}
+
+		var vals []string
+		for _, result := range results {
+			for _, val := range result {
+				if str, ok := jsonPathValue(val); ok {
+					vals = append(vals, str)
+				}
+			}
+		}
+		return vals
+	}, "jsonpath "+template)
+}
+
+// jsonPathValue returns the string form of a value found by a JSONPath.
+func jsonPathValue(val reflect.Value) (string, bool) {
+	if !val.IsValid() || !val.CanInterface() {
+		return "", false
+	}
+
+	switch v := val.Interface().(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case map[string]interface{}, []interface{}:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return "", false
+		}
+		return string(raw), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// IndexValues returns the distinct values, sorted, indexed under the field index registered
+// by IndexField for the given field on the GVK of the given object.
+func (s *CacheStores) IndexValues(obj client.Object, field string) ([]string, error) {
+	if obj == nil {
+		return nil, ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		return nil, ErrGvkNotFound
+	}
+
+	indexName := s.fieldIdxName(field)
+	if _, exists := store.GetIndexers()[indexName]; !exists {
+		return nil, fmt.Errorf("index with name %s does not exist", indexName)
+	}
+
+	// every value is indexed both under the namespace of the object and across all namespaces.
+	seen := make(map[string]struct{})
+	for _, key := range store.ListIndexFuncValues(indexName) {
+		seen[namespacedKeyValue(key)] = struct{}{}
+	}
+
+	vals := make([]string, 0, len(seen))
+	for val := range seen {
+		vals = append(vals, val)
+	}
+	sort.Strings(vals)
+
+	return vals, nil
+}
+
+// ListKeysByField returns the keys, sorted, of the objects of the GVK of the given object
+// whose field indexed by IndexField has the given value, across all namespaces, without
+// copying the objects. The keys are namespace/name keys unless the GVK was registered by
+// RegisterKindWithKeyFunc, and can be split by cache.SplitMetaNamespaceKey for DeleteByKey.
+// It returns an error if the field is not indexed or is indexed with IndexNamespacedOnly.
+func (s *CacheStores) ListKeysByField(obj client.Object, field, value string) ([]string, error) {
+	if obj == nil {
+		return nil, ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		return nil, ErrGvkNotFound
+	}
+
+	indexName := s.fieldIdxName(field)
+	if _, exists := store.GetIndexers()[indexName]; !exists {
+		return nil, fmt.Errorf("index with name %s does not exist", indexName)
+	}
+	if s.namespacedOnly[*gvk][indexName] {
+		return nil, fmt.Errorf("field index %s is namespaced only, its keys can not be listed across namespaces", field)
+	}
+
+	keys, err := store.IndexKeys(indexName, keyToNamespacedKey("", value))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// ReindexAll recomputes every index of the GVK of the given object from the cached objects,
+// e.g. when an index function depends on state which changed since the objects were added.
+// The indexes are rebuilt from scratch, which is O(n) in the number of objects of the GVK
+// times the number of indexes, and blocks every other cache operation meanwhile. Event
+// handlers are not notified, as the objects do not change.
+func (s *CacheStores) ReindexAll(obj client.Object) error {
+	return s.rebuild(obj)
+}
+
+// Compact releases the memory held by the store of the GVK of the given object beyond what
+// its current objects need, e.g. after most of them were deleted or evicted, as Go maps do
+// not shrink. The objects and indexes are moved to maps sized to the current number of
+// objects, which is O(n) in the number of objects of the GVK times the number of indexes,
+// and blocks every other cache operation meanwhile. The indexer returned by GetByType
+// remains valid.
+func (s *CacheStores) Compact(obj client.Object) error {
+	return s.rebuild(obj)
+}
+
+// rebuild replaces the objects of the store of the GVK of the given object with themselves,
+// which makes client-go allocate new maps and compute every index again.
+func (s *CacheStores) rebuild(obj client.Object) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		return ErrGvkNotFound
+	}
+
+	items := store.List()
+	if err := s.checkIndexable(store.GetIndexers(), items...); err != nil {
+		return err
+	}
+
+	return store.Replace(items, "")
+}
+
+// VerifyIndexes checks that every index of the GVK of the given object is consistent with
+// the cached objects: every object must be found in each index under every value its index
+// function computes for it now. The returned error lists the inconsistencies found. It is
+// meant for debugging, as it runs every index function on every object of the GVK.
+func (s *CacheStores) VerifyIndexes(obj client.Object) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		return ErrGvkNotFound
+	}
+
+	indexers := store.GetIndexers()
+	names := make([]string, 0, len(indexers))
+	for name := range indexers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	keyFunc := s.keyFunc(*gvk)
+	items := store.List()
+	if err := sortByName(items); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, item := range items {
+		key, err := keyFunc(item)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			vals, err := s.callIndexFunc(name, indexers[name], item)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, val := range vals {
+				keys, err := store.IndexKeys(name, val)
+				if err != nil {
+					return err
+				}
+				if !slices.Contains(keys, key) {
+					errs = append(errs, fmt.Errorf("object %s is missing from index %s under %q", key, name, val))
+				}
+			}
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestIndexByJSONPathAgain(t *testing.T) {
+	c := newTestCache(t)
+	mustAdd(t, c, deployment("default", "web", map[string]string{"a": "1", "b": "2"}))
+
+	if err := c.IndexByJSONPath(&appsv1.Deployment{}, "idx", ".metadata.labels.a"); err != nil {
+		t.Fatalf("failed to index the path: %v", err)
+	}
+	if err := c.IndexByJSONPath(&appsv1.Deployment{}, "idx", ".metadata.labels.a"); err != nil {
+		t.Errorf("indexing the same path again failed: %v", err)
+	}
+	if err := c.IndexByJSONPath(&appsv1.Deployment{}, "idx", ".metadata.labels.b"); !errors.Is(err, ErrIndexExists) {
+		t.Errorf("got error %v indexing another path, want ErrIndexExists", err)
+	}
+
+	vals, err := c.IndexValues(&appsv1.Deployment{}, "idx")
+	if err != nil {
+		t.Fatalf("failed to get the index values: %v", err)
+	}
+	if want := []string{"1"}; !slices.Equal(vals, want) {
+		t.Errorf("got values %v, want %v", vals, want)
+	}
+}
+
+func TestIndexFieldAgain(t *testing.T) {
+	c := newTestCache(t)
+	byLabel := func(key string) client.IndexerFunc {
+		return func(o client.Object) []string {
+			return []string{o.GetLabels()[key]}
+		}
+	}
+
+	if err := c.IndexField(&appsv1.Deployment{}, "idx", byLabel("a")); err != nil {
+		t.Fatalf("failed to index the field: %v", err)
+	}
+	// closures of the same literal may extract different values, so they are not equivalent.
+	if err := c.IndexField(&appsv1.Deployment{}, "idx", byLabel("a")); !errors.Is(err, ErrIndexExists) {
+		t.Errorf("got error %v indexing the field again, want ErrIndexExists", err)
+	}
+	if err := c.IndexField(&appsv1.Deployment{}, "metadata.name", byLabel("a")); err != nil {
+		t.Errorf("indexing an implicit field failed: %v", err)
+	}
+
+	if err := c.DeleteIndex(&appsv1.Deployment{}, "idx"); err != nil {
+		t.Fatalf("failed to delete the index: %v", err)
+	}
+	if err := c.IndexField(&appsv1.Deployment{}, "idx", byLabel("b")); err != nil {
+		t.Errorf("failed to index the field once deleted: %v", err)
+	}
+}
+
+func TestIndexPrefixOfOtherIndexes(t *testing.T) {
+	for _, prefix := range []string{"", "label:", "composite:"} {
+		if _, err := New(newTestScheme(), WithIndexPrefix(prefix)); err == nil {
+			t.Errorf("expected an error creating the cache with the field index prefix %q", prefix)
+		}
+	}
+
+	// with a distinct prefix, a field and a label index of the same name coexist.
+	c := newTestCache(t, WithIndexPrefix("f:"))
+	mustAdd(t, c, deployment("default", "web", map[string]string{"app": "shop"}))
+	if err := c.IndexField(&appsv1.Deployment{}, "app", func(o client.Object) []string {
+		return []string{o.GetName()}
+	}); err != nil {
+		t.Fatalf("failed to index the field: %v", err)
+	}
+	if err := c.IndexLabel(&appsv1.Deployment{}, "app"); err != nil {
+		t.Fatalf("failed to index the label: %v", err)
+	}
+
+	want := []string{"default/web"}
+	if got := listDeploymentKeys(t, c, client.MatchingLabels{"app": "shop"}); !slices.Equal(got, want) {
+		t.Errorf("got %v listed by label, want %v", got, want)
+	}
+	if got := listDeploymentKeys(t, c, client.MatchingFields{"app": "web"}); !slices.Equal(got, want) {
+		t.Errorf("got %v listed by field, want %v", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// loadCall is a load of an object from the loader, shared by the concurrent readers
+// missing the same object.
+type loadCall struct {
+	done chan struct{}
+	err  error
+}
+
+// SetLoader sets the reader Get and GetInto fall back to when an object is not cached,
+// e.g. a client of the API server, which makes the cache a read-through cache. Loaded
+// objects are added to the cache, and concurrent misses of the same object share a single
+// load. A nil reader disables the fallback.
+func (s *CacheStores) SetLoader(r client.Reader) {
+	s.loadsMu.Lock()
+	defer s.loadsMu.Unlock()
+
+	s.loader = r
+}
+
+// getOrLoad returns the object of the given GVK and key looked up by get, loading it from
+// the loader, if any, when it is not cached.
+func (s *CacheStores) getOrLoad(gvk schema.GroupVersionKind, objKey client.ObjectKey, get func() (interface{}, bool, error)) (interface{}, bool, error) {
+	item, exists, err := get()
+	if err != nil || exists {
+		return item, exists, err
+	}
+
+	s.loadsMu.Lock()
+	loader := s.loader
+	if loader == nil {
+		s.loadsMu.Unlock()
+		return nil, false, nil
+	}
+
+	callKey := gvk.String() + "|" + objKey.String()
+	call, loading := s.loads[callKey]
+	if !loading {
+		call = &loadCall{done: make(chan struct{})}
+		if s.loads == nil {
+			s.loads = make(map[string]*loadCall)
+		}
+		s.loads[callKey] = call
+	}
+	s.loadsMu.Unlock()
+
+	if loading {
+		<-call.done
+	} else {
+		call.err = s.load(loader, gvk, objKey)
+
+		s.loadsMu.Lock()
+		delete(s.loads, callKey)
+		s.loadsMu.Unlock()
+		close(call.done)
+	}
+	if call.err != nil {
+		return nil, false, call.err
+	}
+
+	return get()
+}
+
+// load gets the object of the given GVK and key from the loader and adds it to the cache.
+// Objects missing from the loader are not an error.
+func (s *CacheStores) load(loader client.Reader, gvk schema.GroupVersionKind, objKey client.ObjectKey) error {
+	obj, err := s.newObject(gvk)
+	if err != nil {
+		return err
+	}
+
+	if err := loader.Get(context.Background(), objKey, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+
+	return s.Add(obj)
+}
+
+func (s *CacheStores) hasLoader() bool {
+	s.loadsMu.Lock()
+	defer s.loadsMu.Unlock()
+
+	return s.loader != nil
+}
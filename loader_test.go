@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// blockingLoader is a client.Reader whose Get blocks until release is closed, then returns
+// err or a deployment labelled with the number of the call.
+type blockingLoader struct {
+	client.Reader
+	calls   atomic.Int32
+	release chan struct{}
+	err     error
+}
+
+func (l *blockingLoader) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	n := l.calls.Add(1)
+	<-l.release
+	if l.err != nil {
+		return l.err
+	}
+
+	obj.SetNamespace(key.Namespace)
+	obj.SetName(key.Name)
+	obj.SetLabels(map[string]string{"call": strconv.Itoa(int(n))})
+	return nil
+}
+
+// getConcurrently calls Get of the web deployment from n goroutines, and releases the loader
+// once they all had the time to miss it.
+func getConcurrently(c *CacheStores, loader *blockingLoader, n int) ([]interface{}, []error) {
+	items := make([]interface{}, n)
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			items[i], _, errs[i] = c.Get(deployment("default", "web", nil))
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(loader.release)
+	wg.Wait()
+
+	return items, errs
+}
+
+func TestLoaderSingleFlight(t *testing.T) {
+	c := newTestCache(t)
+	loader := &blockingLoader{release: make(chan struct{})}
+	c.SetLoader(loader)
+
+	items, errs := getConcurrently(c, loader, 10)
+	if n := loader.calls.Load(); n != 1 {
+		t.Errorf("got %d loads, want 1", n)
+	}
+	for i := range items {
+		if errs[i] != nil {
+			t.Errorf("got error %v", errs[i])
+			continue
+		}
+		obj, ok := items[i].(*appsv1.Deployment)
+		if !ok {
+			t.Errorf("got %T, want *appsv1.Deployment", items[i])
+			continue
+		}
+		if got := obj.Labels["call"]; got != "1" {
+			t.Errorf("got the object of load %q, want the one of load 1", got)
+		}
+	}
+	if n, err := c.Count(&appsv1.Deployment{}); err != nil || n != 1 {
+		t.Errorf("got %d deployments cached, err %v, want 1", n, err)
+	}
+}
+
+func TestLoaderSingleFlightError(t *testing.T) {
+	c := newTestCache(t)
+	loadErr := errors.New("load failed")
+	loader := &blockingLoader{release: make(chan struct{}), err: loadErr}
+	c.SetLoader(loader)
+
+	_, errs := getConcurrently(c, loader, 10)
+	if n := loader.calls.Load(); n != 1 {
+		t.Errorf("got %d loads, want 1", n)
+	}
+	for _, err := range errs {
+		if !errors.Is(err, loadErr) {
+			t.Errorf("got error %v, want %v", err, loadErr)
+		}
+	}
+
+	// the failed load is not remembered, the next miss loads again.
+	if _, _, err := c.Get(deployment("default", "web", nil)); !errors.Is(err, loadErr) {
+		t.Errorf("got error %v, want %v", err, loadErr)
+	}
+	if n := loader.calls.Load(); n != 2 {
+		t.Errorf("got %d loads, want 2", n)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// SetLogger sets the logger used to report index registrations and evictions at debug
+// level (V(1)), and event handler panics at error level. The cache does not log unless a
+// logger is set.
+func (s *CacheStores) SetLogger(logger logr.Logger) {
+	s.logger.Store(&logger)
+}
+
+func (s *CacheStores) log() logr.Logger {
+	if logger := s.logger.Load(); logger != nil {
+		return *logger
+	}
+	return logr.Discard()
+}
+
+// notifyHandlers calls notify on every handler, recovering and logging the panics of the
+// handlers so that a faulty handler neither skips the others nor crashes the caller.
+func (s *CacheStores) notifyHandlers(event string, handlers []cache.ResourceEventHandler, notify func(cache.ResourceEventHandler)) {
+	for _, h := range handlers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					s.log().Error(fmt.Errorf("panic: %v", r), "event handler panicked",
+						"event", event, "handler", handlerType(h), "stack", string(debug.Stack()))
+				}
+			}()
+			notify(h)
+		}()
+	}
+}
+
+// handlerType returns the type of the given handler, or of the handler it wraps if it is a
+// replayGate.
+func handlerType(h cache.ResourceEventHandler) string {
+	if gate, ok := h.(*replayGate); ok {
+		h = gate.handler
+	}
+	return fmt.Sprintf("%T", h)
+}
+
+func (s *CacheStores) logIndexRegistered(gvk schema.GroupVersionKind, indexName string) {
+	s.log().V(1).Info("registered index", "gvk", gvk.String(), "index", indexName)
+}
@@ -32,9 +32,16 @@ func main() {
 	}
 
 	deploy := appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Deployment",
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "deploy",
 			Namespace: "default",
+			Labels: map[string]string{
+				appLabel: appLabelVal,
+			},
 			Annotations: map[string]string{
 				dummyAnnotation: dummyAnnotationVal,
 			},
@@ -78,15 +85,28 @@ func main() {
 	}
 
 	log.Printf("found %d deployments using custom index\n", len(deploys.Items))
+
+	err = cacheStores.List(&deploys,
+		client.InNamespace(deploy.Namespace),
+		client.MatchingLabels{appLabel: appLabelVal},
+		client.MatchingFields{customIdx: dummyAnnotationVal},
+	)
+	if err != nil {
+		log.Fatalf("failed to list deployments, err: %v", err)
+	}
+
+	log.Printf("found %d deployments in namespace %s using labels and custom index\n", len(deploys.Items), deploy.Namespace)
 }
 
 const (
 	customIdx          = "my_custom_index"
 	dummyAnnotation    = "my.domain/label"
 	dummyAnnotationVal = "someval"
+	appLabel           = "app"
+	appLabelVal        = "web"
 )
 
-func setupCacheIndexes(stores CacheStores) error {
+func setupCacheIndexes(stores *CacheStores) error {
 	err := stores.IndexField(
 		&appsv1.Deployment{},
 		customIdx,
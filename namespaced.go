@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NamespacedCache is a view of CacheStores confined to a single namespace. It shares the
+// underlying stores, so objects added through it are visible through CacheStores and vice
+// versa.
+type NamespacedCache struct {
+	stores    *CacheStores
+	namespace string
+}
+
+// Namespaced returns a view of the cache confined to the given namespace. Objects without
+// a namespace are defaulted to it, and objects of another namespace are rejected. The
+// objects of the kinds the RESTMapper knows as cluster-scoped, see WithRESTMapper, are
+// passed through unchanged; without a mapper, every kind is assumed to be namespaced.
+func (s *CacheStores) Namespaced(namespace string) *NamespacedCache {
+	return &NamespacedCache{stores: s, namespace: namespace}
+}
+
+// Namespace returns the namespace the view is confined to.
+func (c *NamespacedCache) Namespace() string {
+	return c.namespace
+}
+
+// Get is like CacheStores.Get within the namespace of the view.
+func (c *NamespacedCache) Get(obj client.Object) (item interface{}, exists bool, err error) {
+	obj, err = c.inNamespace(obj)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return c.stores.Get(obj)
+}
+
+// List is like CacheStores.List within the namespace of the view, whatever the namespace
+// given through the list options.
+func (c *NamespacedCache) List(out client.ObjectList, opts ...client.ListOption) error {
+	opts = append(opts[:len(opts):len(opts)], client.InNamespace(c.namespace))
+	return c.stores.List(out, opts...)
+}
+
+// Add is like CacheStores.Add within the namespace of the view.
+func (c *NamespacedCache) Add(obj client.Object) error {
+	obj, err := c.inNamespace(obj)
+	if err != nil {
+		return err
+	}
+
+	return c.stores.Add(obj)
+}
+
+// Delete is like CacheStores.Delete within the namespace of the view.
+func (c *NamespacedCache) Delete(obj client.Object) error {
+	obj, err := c.inNamespace(obj)
+	if err != nil {
+		return err
+	}
+
+	return c.stores.Delete(obj)
+}
+
+// inNamespace returns the given object, or a copy of it in the namespace of the view if it
+// has no namespace. It returns an error if the object belongs to another namespace.
+// Cluster-scoped objects, as told by the RESTMapper, are returned unchanged.
+func (c *NamespacedCache) inNamespace(obj client.Object) (client.Object, error) {
+	if obj == nil {
+		return nil, ErrNilObj
+	}
+
+	gvk, err := c.stores.gvkFor(obj)
+	if err != nil {
+		return nil, err
+	}
+	c.stores.mu.RLock()
+	clusterScoped, err := c.stores.isClusterScoped(*gvk)
+	c.stores.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if clusterScoped {
+		return obj, nil
+	}
+
+	switch obj.GetNamespace() {
+	case c.namespace:
+		return obj, nil
+	case "":
+		obj = obj.DeepCopyObject().(client.Object)
+		obj.SetNamespace(c.namespace)
+		return obj, nil
+	default:
+		return nil, fmt.Errorf("object %s is not in namespace %q", client.ObjectKeyFromObject(obj), c.namespace)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// newTestRESTMapper returns a mapper knowing Deployments as namespaced and ClusterRoles as
+// cluster-scoped.
+func newTestRESTMapper() apimeta.RESTMapper {
+	mapper := apimeta.NewDefaultRESTMapper([]schema.GroupVersion{appsv1.SchemeGroupVersion, rbacv1.SchemeGroupVersion})
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), apimeta.RESTScopeNamespace)
+	mapper.Add(rbacv1.SchemeGroupVersion.WithKind("ClusterRole"), apimeta.RESTScopeRoot)
+	return mapper
+}
+
+func TestNamespacedDefaultsNamespace(t *testing.T) {
+	c := newTestCache(t, WithRESTMapper(newTestRESTMapper()))
+	view := c.Namespaced("tenant")
+
+	if err := view.Add(deployment("", "web", nil)); err != nil {
+		t.Fatalf("failed to add through the view: %v", err)
+	}
+	if _, exists, err := c.Get(deployment("tenant", "web", nil)); err != nil || !exists {
+		t.Errorf("got exists %v, err %v for tenant/web, want it cached", exists, err)
+	}
+	if err := view.Add(deployment("other", "web", nil)); err == nil {
+		t.Error("expected an error adding an object of another namespace")
+	}
+}
+
+func TestNamespacedPassesClusterScopedObjects(t *testing.T) {
+	c := newTestCache(t, WithRESTMapper(newTestRESTMapper()))
+	view := c.Namespaced("tenant")
+	clusterRole := func() *rbacv1.ClusterRole {
+		return &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "cr"}}
+	}
+
+	if err := view.Add(clusterRole()); err != nil {
+		t.Fatalf("failed to add through the view: %v", err)
+	}
+	if _, exists, err := c.Get(clusterRole()); err != nil || !exists {
+		t.Errorf("got exists %v, err %v for the cluster role, want it cached without namespace", exists, err)
+	}
+	if _, exists, err := view.Get(clusterRole()); err != nil || !exists {
+		t.Errorf("got exists %v, err %v through the view, want it found", exists, err)
+	}
+
+	if err := view.Delete(clusterRole()); err != nil {
+		t.Fatalf("failed to delete through the view: %v", err)
+	}
+	if _, exists, _ := c.Get(clusterRole()); exists {
+		t.Error("the cluster role is still cached once deleted through the view")
+	}
+}
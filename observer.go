@@ -0,0 +1,48 @@
+package main
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Observer is notified about the operations performed on the cache, e.g. to export metrics.
+// The callbacks run inline with the observed operations, so they must be fast and must not
+// call back into the cache.
+type Observer interface {
+	// OnAdd is called after an object was added, or replaced, in the store of the given GVK,
+	// including by Update, UpdateStatus and Patch.
+	OnAdd(gvk schema.GroupVersionKind)
+	// OnDelete is called after an object was deleted from the store of the given GVK.
+	OnDelete(gvk schema.GroupVersionKind)
+	// OnGet is called after an object was looked up in the store of the given GVK.
+	OnGet(gvk schema.GroupVersionKind, found bool)
+	// OnList is called after the objects of the given GVK were listed.
+	OnList(gvk schema.GroupVersionKind, duration time.Duration, resultCount int)
+}
+
+// observerHolder allows storing an Observer interface in an atomic.Pointer.
+type observerHolder struct {
+	Observer
+}
+
+// noopObserver is used when no Observer is set.
+type noopObserver struct{}
+
+func (noopObserver) OnAdd(schema.GroupVersionKind)                      {}
+func (noopObserver) OnDelete(schema.GroupVersionKind)                   {}
+func (noopObserver) OnGet(schema.GroupVersionKind, bool)                {}
+func (noopObserver) OnList(schema.GroupVersionKind, time.Duration, int) {}
+
+// SetObserver sets the observer notified about the cache operations. A nil observer
+// disables the notifications.
+func (s *CacheStores) SetObserver(o Observer) {
+	s.observer.Store(&observerHolder{Observer: o})
+}
+
+func (s *CacheStores) observe() Observer {
+	if h := s.observer.Load(); h != nil && h.Observer != nil {
+		return h.Observer
+	}
+	return noopObserver{}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// countingObserver counts the operations reported to it.
+type countingObserver struct {
+	mu      sync.Mutex
+	adds    int
+	deletes int
+}
+
+func (o *countingObserver) OnAdd(schema.GroupVersionKind) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.adds++
+}
+
+func (o *countingObserver) OnDelete(schema.GroupVersionKind) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.deletes++
+}
+
+func (o *countingObserver) OnGet(schema.GroupVersionKind, bool)                {}
+func (o *countingObserver) OnList(schema.GroupVersionKind, time.Duration, int) {}
+
+func TestObserverCountsWrites(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(c *CacheStores) error
+	}{
+		{name: "add", write: func(c *CacheStores) error {
+			return c.Add(deployment("default", "web", nil))
+		}},
+		{name: "update", write: func(c *CacheStores) error {
+			return c.Update(deployment("default", "web", nil))
+		}},
+		{name: "update status", write: func(c *CacheStores) error {
+			return c.UpdateStatus(deployment("default", "web", nil))
+		}},
+		{name: "patch", write: func(c *CacheStores) error {
+			patch := client.RawPatch(types.MergePatchType, []byte(`{"metadata":{"labels":{"app":"web"}}}`))
+			return c.Patch(deployment("default", "web", nil), patch)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observer := &countingObserver{}
+			c := newTestCache(t, WithObserver(observer))
+			if err := c.EnableStatusSubresource(&appsv1.Deployment{}); err != nil {
+				t.Fatalf("failed to enable the status subresource: %v", err)
+			}
+			mustAdd(t, c, deployment("default", "web", nil))
+
+			if err := tt.write(c); err != nil {
+				t.Fatalf("failed to write the deployment: %v", err)
+			}
+			if observer.adds != 2 {
+				t.Errorf("got %d OnAdd calls, want 2", observer.adds)
+			}
+
+			if err := c.Delete(deployment("default", "web", nil)); err != nil {
+				t.Fatalf("failed to delete the deployment: %v", err)
+			}
+			if observer.deletes != 1 {
+				t.Errorf("got %d OnDelete calls, want 1", observer.deletes)
+			}
+		})
+	}
+}
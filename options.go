@@ -0,0 +1,151 @@
+package main
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// listOptions extends client.ListOptions with the options only understood by the cache.
+type listOptions struct {
+	client.ListOptions
+
+	excludeTerminating bool
+	unsorted           bool
+	filters            []FilterFunc
+	// total, if set, is incremented for every object matching the options.
+	total *int
+	// resourceVersion, if set, is set to the resource version of the cache when listing.
+	resourceVersion *string
+	projection      Projection
+}
+
+// cacheListOption is implemented by the list options only understood by the cache.
+// They also implement client.ListOption as a no-op so that they can be passed to List
+// along with the controller-runtime options.
+type cacheListOption interface {
+	applyToCacheList(*listOptions)
+}
+
+func (o *listOptions) applyOptions(opts []client.ListOption) {
+	o.ListOptions.ApplyOptions(opts)
+	for _, opt := range opts {
+		if cacheOpt, ok := opt.(cacheListOption); ok {
+			cacheOpt.applyToCacheList(o)
+		}
+	}
+}
+
+// ExcludeTerminating is a list option dropping the objects being deleted, i.e. the ones
+// with a deletion timestamp. By default, List returns terminating objects as well.
+type ExcludeTerminating struct{}
+
+// ApplyToList implements client.ListOption.
+func (ExcludeTerminating) ApplyToList(*client.ListOptions) {}
+
+func (ExcludeTerminating) applyToCacheList(o *listOptions) {
+	o.excludeTerminating = true
+}
+
+// Unsorted is a list option skipping the sort of the listed objects, which are otherwise
+// ordered by namespace, then by name. It saves the sort on large lists whose order does
+// not matter; lists using the Limit or Continue options are sorted regardless.
+type Unsorted struct{}
+
+// ApplyToList implements client.ListOption.
+func (Unsorted) ApplyToList(*client.ListOptions) {}
+
+func (Unsorted) applyToCacheList(o *listOptions) {
+	o.unsorted = true
+}
+
+// countTotal makes List count the objects matching the options into total.
+type countTotal struct {
+	total *int
+}
+
+// ApplyToList implements client.ListOption.
+func (countTotal) ApplyToList(*client.ListOptions) {}
+
+func (c countTotal) applyToCacheList(o *listOptions) {
+	o.total = c.total
+}
+
+// snapshotVersion makes List report the resource version of the cache at the time of the
+// listing into resourceVersion.
+type snapshotVersion struct {
+	resourceVersion *string
+}
+
+// ApplyToList implements client.ListOption.
+func (snapshotVersion) ApplyToList(*client.ListOptions) {}
+
+func (v snapshotVersion) applyToCacheList(o *listOptions) {
+	o.resourceVersion = v.resourceVersion
+}
+
+// FilterFunc is a list option keeping only the objects for which it returns true. It allows
+// filtering on what selectors cannot express; multiple FilterFuncs must all return true.
+// The function is given the cached object itself, so it must not modify it.
+type FilterFunc func(client.Object) bool
+
+// ApplyToList implements client.ListOption.
+func (FilterFunc) ApplyToList(*client.ListOptions) {}
+
+func (f FilterFunc) applyToCacheList(o *listOptions) {
+	if f != nil {
+		o.filters = append(o.filters, f)
+	}
+}
+
+// matchesFilters reports whether obj passes all the given FilterFunc options. A panicking
+// filter is reported as an error.
+func (s *CacheStores) matchesFilters(filters []FilterFunc, obj runtime.Object) (bool, error) {
+	if len(filters) == 0 {
+		return true, nil
+	}
+	clientObj, ok := obj.(client.Object)
+	if !ok {
+		return false, nil
+	}
+	for _, filter := range filters {
+		matches, err := s.callFilter(filter, clientObj)
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Projection is a list option handing the matching objects to the function instead of
+// returning them in the list, whose items are left empty, e.g. to extract a few fields
+// without converting whole objects to the list type. The function is given a copy of each
+// object, in the representation it was added in, which it may modify or retain. Only the
+// last Projection given is used.
+type Projection func(client.Object)
+
+// ApplyToList implements client.ListOption.
+func (Projection) ApplyToList(*client.ListOptions) {}
+
+func (p Projection) applyToCacheList(o *listOptions) {
+	o.projection = p
+}
+
+type indexOptions struct {
+	namespacedOnly bool
+}
+
+// IndexOption configures an index registered by IndexField.
+type IndexOption func(*indexOptions)
+
+// IndexNamespacedOnly makes IndexField index the values of namespaced objects only under
+// their namespace. By default, every value is indexed twice, under the namespace of the
+// object and across all namespaces, which doubles the memory used by the index. Without
+// the all-namespaces entries, listing with a field selector on the index requires the
+// InNamespace option, and is rejected otherwise. The index is for namespaced kinds only:
+// IndexField returns an error if the GVK is cluster-scoped according to the RESTMapper or
+// if an object without a namespace is cached, and such objects are rejected when added.
+func IndexNamespacedOnly() IndexOption {
+	return func(o *indexOptions) {
+		o.namespacedOnly = true
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// encodeContinueToken returns the continue token resuming a list after the given key.
+// A token is the URL-safe base64 encoding, without padding, of the page key of the last
+// object returned by the previous page.
+func encodeContinueToken(lastKey string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(lastKey))
+}
+
+// decodeContinueToken returns the page key of the last object returned by the previous
+// page, whose key is a store key if customKeyed is set, see pageKey.
+func decodeContinueToken(token string, customKeyed bool) (pageKey, error) {
+	key, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return pageKey{}, fmt.Errorf("invalid continue token %q: %w", token, err)
+	}
+	if len(key) == 0 {
+		return pageKey{}, fmt.Errorf("invalid continue token %q: empty key", token)
+	}
+	if customKeyed {
+		return pageKey{customKeyed: true, storeKey: string(key)}, nil
+	}
+
+	name, err := cache.ParseObjectName(string(key))
+	if err != nil {
+		return pageKey{}, fmt.Errorf("invalid continue token %q: %w", token, err)
+	}
+
+	return pageKey{name: name}, nil
+}
+
+// pageKey is the position of an object in the lists: its namespace and name, or its store
+// key for the kinds registered with RegisterKindWithKeyFunc, whose objects may share a
+// namespace and name.
+type pageKey struct {
+	customKeyed bool
+	name        cache.ObjectName
+	storeKey    string
+}
+
+// String returns the key stored in the continue tokens.
+func (k pageKey) String() string {
+	if k.customKeyed {
+		return k.storeKey
+	}
+	return k.name.String()
+}
+
+// pageKeyFunc returns the function computing the page keys of the objects of a kind, keyed
+// by the given function if it is a custom one, by namespace and name if it is nil.
+func pageKeyFunc(customKeyFunc cache.KeyFunc) func(obj interface{}) (pageKey, error) {
+	if customKeyFunc != nil {
+		return func(obj interface{}) (pageKey, error) {
+			key, err := customKeyFunc(obj)
+			return pageKey{customKeyed: true, storeKey: key}, err
+		}
+	}
+
+	return func(obj interface{}) (pageKey, error) {
+		meta, err := apimeta.Accessor(obj)
+		if err != nil {
+			return pageKey{}, err
+		}
+		return pageKey{name: objectName(meta)}, nil
+	}
+}
+
+// comparePageKeys orders page keys by namespace, then by name, or by store key.
+func comparePageKeys(a, b pageKey) int {
+	if c := compareObjectNames(a.name, b.name); c != 0 {
+		return c
+	}
+	return strings.Compare(a.storeKey, b.storeKey)
+}
+
+// sortByPageKey sorts the given objects by the page keys computed by keyOf.
+func sortByPageKey(objs []interface{}, keyOf func(obj interface{}) (pageKey, error)) error {
+	keys := make(map[interface{}]pageKey, len(objs))
+	for _, obj := range objs {
+		key, err := keyOf(obj)
+		if err != nil {
+			return err
+		}
+		keys[obj] = key
+	}
+
+	sort.SliceStable(objs, func(i, j int) bool {
+		return comparePageKeys(keys[objs[i]], keys[objs[j]]) < 0
+	})
+
+	return nil
+}
+
+// objectName returns the namespace and name of the given object.
+func objectName(meta metav1.Object) cache.ObjectName {
+	return cache.NewObjectName(meta.GetNamespace(), meta.GetName())
+}
+
+// compareObjectNames orders object names by namespace, then by name.
+func compareObjectNames(a, b cache.ObjectName) int {
+	if c := strings.Compare(a.Namespace, b.Namespace); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Name, b.Name)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Patch applies the given patch to the cached object identified by obj, stores the result
+// with a bumped resource version and writes it back into obj. JSON, merge and strategic
+// merge patches are supported; strategic merge patches require the GVK to be registered in
+// the scheme. It returns ErrNotFound if the object is not cached, a Conflict API error if the
+// patch sets a stale resource version, and a BadRequest API error if it changes the
+// namespace, the name or the key of the object.
+func (s *CacheStores) Patch(obj client.Object, patch client.Patch) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+	if patch == nil {
+		return fmt.Errorf("cannot apply nil patch")
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		s.mu.Unlock()
+		return ErrNotFound
+	}
+
+	var patched client.Object
+	old, exists, err := store.Get(withGVK(obj, *gvk))
+	if err == nil && !exists {
+		err = ErrNotFound
+	}
+	if err == nil {
+		patched, err = s.applyPatch(old, patch.Type(), data)
+	}
+	if err == nil {
+		patched, err = s.transformed(*gvk, patched)
+	}
+	if err == nil {
+		err = s.checkSameKey(*gvk, old, patched)
+	}
+	if err == nil {
+		patched.GetObjectKind().SetGroupVersionKind(*gvk)
+		err = nextResourceVersion(*gvk, old, patched)
+	}
+	if err == nil {
+		patched, err = s.keepStatus(*gvk, old, patched)
+	}
+	if err == nil {
+		err = s.checkIndexable(store.GetIndexers(), patched)
+	}
+	if err == nil {
+		err = store.Update(patched)
+	}
+	if err == nil {
+		s.trackModified(*gvk, patched)
+		s.bumpGeneration(*gvk)
+	}
+	handlers := s.reserveHandlers(*gvk)
+	defer releaseHandlers(handlers)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	s.observe().OnAdd(*gvk)
+	s.notifyUpdate(handlers, old, patched)
+
+	converted, err := convertObjects([]runtime.Object{patched}, representationOf(obj), s.schemeFor(*gvk), *gvk)
+	if err != nil {
+		return err
+	}
+
+	return copyInto(converted[0], obj, *gvk)
+}
+
+// applyPatch returns a new object, of the same type as old, holding the result of applying
+// the given patch to old.
+func (s *CacheStores) applyPatch(old interface{}, patchType types.PatchType, data []byte) (client.Object, error) {
+	oldObj, ok := old.(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("cache contained %T, which is not a client.Object", old)
+	}
+
+	original, err := json.Marshal(oldObj)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []byte
+	switch patchType {
+	case types.JSONPatchType:
+		var p jsonpatch.Patch
+		p, err = jsonpatch.DecodePatch(data)
+		if err != nil {
+			return nil, err
+		}
+		result, err = p.Apply(original)
+	case types.MergePatchType:
+		result, err = jsonpatch.MergePatch(original, data)
+	case types.StrategicMergePatchType:
+		gvk := oldObj.GetObjectKind().GroupVersionKind()
+		var dataStruct runtime.Object
+		dataStruct, err = s.schemeFor(gvk).New(gvk)
+		if err != nil {
+			return nil, fmt.Errorf("strategic merge patches require a structured type: %w", err)
+		}
+		result, err = strategicpatch.StrategicMergePatch(original, data, dataStruct)
+	default:
+		return nil, fmt.Errorf("unsupported patch type %s", patchType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	patched, ok := reflect.New(reflect.TypeOf(oldObj).Elem()).Interface().(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("cannot create an object of type %T", oldObj)
+	}
+	if err := json.Unmarshal(result, patched); err != nil {
+		return nil, err
+	}
+
+	return patched, nil
+}
+
+// checkSameKey returns a BadRequest API error if the patched object has another namespace,
+// name or key than the cached one, which would store it next to the cached one. The
+// apiserver rejects such patches as well.
+func (s *CacheStores) checkSameKey(gvk schema.GroupVersionKind, old interface{}, patched client.Object) error {
+	oldObj, ok := old.(client.Object)
+	if !ok {
+		return fmt.Errorf("cache contained %T, which is not a client.Object", old)
+	}
+	if oldKey, newKey := client.ObjectKeyFromObject(oldObj), client.ObjectKeyFromObject(patched); oldKey != newKey {
+		return apierrors.NewBadRequest(fmt.Sprintf("the patch changes %s %s into %s", gvk.Kind, oldKey, newKey))
+	}
+
+	keyFunc := s.keyFunc(gvk)
+	oldKey, err := keyFunc(old)
+	if err != nil {
+		return err
+	}
+	newKey, err := keyFunc(patched)
+	if err != nil {
+		return err
+	}
+	if oldKey != newKey {
+		return apierrors.NewBadRequest(fmt.Sprintf("the patch changes the key of %s %s from %q to %q",
+			gvk.Kind, client.ObjectKeyFromObject(oldObj), oldKey, newKey))
+	}
+
+	return nil
+}
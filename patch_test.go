@@ -0,0 +1,107 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch client.Patch
+	}{
+		{
+			name:  "merge patch",
+			patch: client.RawPatch(types.MergePatchType, []byte(`{"metadata":{"labels":{"app":"shop"}}}`)),
+		},
+		{
+			name:  "json patch",
+			patch: client.RawPatch(types.JSONPatchType, []byte(`[{"op":"replace","path":"/metadata/labels/app","value":"shop"}]`)),
+		},
+		{
+			name:  "strategic merge patch",
+			patch: client.RawPatch(types.StrategicMergePatchType, []byte(`{"metadata":{"labels":{"app":"shop"}}}`)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(t)
+			mustAdd(t, c, deployment("default", "web", map[string]string{"app": "web", "tier": "frontend"}))
+
+			obj := deployment("default", "web", nil)
+			if err := c.Patch(obj, tt.patch); err != nil {
+				t.Fatalf("failed to patch: %v", err)
+			}
+			want := map[string]string{"app": "shop", "tier": "frontend"}
+			if obj.Labels["app"] != want["app"] || obj.Labels["tier"] != want["tier"] {
+				t.Errorf("got labels %v written back, want %v", obj.Labels, want)
+			}
+
+			item, exists, err := c.Get(deployment("default", "web", nil))
+			if err != nil || !exists {
+				t.Fatalf("failed to get: exists %v, err %v", exists, err)
+			}
+			got := item.(*appsv1.Deployment)
+			if got.Labels["app"] != want["app"] || got.Labels["tier"] != want["tier"] {
+				t.Errorf("got labels %v cached, want %v", got.Labels, want)
+			}
+			if got.ResourceVersion != obj.ResourceVersion || got.ResourceVersion == "" {
+				t.Errorf("got resource version %q cached and %q written back", got.ResourceVersion, obj.ResourceVersion)
+			}
+		})
+	}
+}
+
+func TestPatchRejectsKeyChanges(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch client.Patch
+	}{
+		{
+			name:  "name by merge patch",
+			patch: client.RawPatch(types.MergePatchType, []byte(`{"metadata":{"name":"b"}}`)),
+		},
+		{
+			name:  "namespace by merge patch",
+			patch: client.RawPatch(types.MergePatchType, []byte(`{"metadata":{"namespace":"other"}}`)),
+		},
+		{
+			name:  "name by json patch",
+			patch: client.RawPatch(types.JSONPatchType, []byte(`[{"op":"replace","path":"/metadata/name","value":"b"}]`)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(t)
+			mustAdd(t, c, deployment("default", "a", nil))
+
+			err := c.Patch(deployment("default", "a", nil), tt.patch)
+			if !apierrors.IsBadRequest(err) {
+				t.Errorf("got error %v, want a BadRequest error", err)
+			}
+			if got, want := listDeploymentKeys(t, c), []string{"default/a"}; !slices.Equal(got, want) {
+				t.Errorf("got %v cached, want %v", got, want)
+			}
+		})
+	}
+
+	t.Run("custom key", func(t *testing.T) {
+		c := newCustomKeyedCache(t)
+		mustAdd(t, c, withID(deployment("default", "a", nil), "1"))
+
+		patch := client.RawPatch(types.MergePatchType, []byte(`{"metadata":{"annotations":{"id":"2"}}}`))
+		err := c.Patch(withID(deployment("default", "a", nil), "1"), patch)
+		if !apierrors.IsBadRequest(err) {
+			t.Errorf("got error %v, want a BadRequest error", err)
+		}
+		n, err := c.Count(&appsv1.Deployment{})
+		if err != nil || n != 1 {
+			t.Errorf("got %d deployments, err %v, want 1", n, err)
+		}
+	})
+}
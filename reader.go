@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// cacheReader adapts CacheStores to the controller-runtime client.Reader interface.
+type cacheReader struct {
+	stores *CacheStores
+}
+
+var _ client.Reader = &cacheReader{}
+
+// Reader returns a client.Reader backed by the cache, which makes it possible to use the
+// cache as a read-only client, e.g. in reconciler tests.
+func (s *CacheStores) Reader() client.Reader {
+	return &cacheReader{stores: s}
+}
+
+// Get populates obj with the cached object stored under the given key. It returns a
+// NotFound API error if the object is not cached.
+func (r *cacheReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return r.stores.GetInto(key, obj)
+}
+
+// List populates list with the cached objects matching the given options.
+func (r *cacheReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return r.stores.ListCtx(ctx, list, opts...)
+}
+
+// objectKeyToStoreKey converts the given key to the key used by cache.MetaNamespaceKeyFunc.
+func objectKeyToStoreKey(key client.ObjectKey) string {
+	return cache.NewObjectName(key.Namespace, key.Name).String()
+}
+
+// getByObjectKey is like GetByKey but looks the object up by namespace and name, which also
+// finds the objects of the GVKs registered by RegisterKindWithKeyFunc.
+func (s *CacheStores) getByObjectKey(gvk schema.GroupVersionKind, key client.ObjectKey) (interface{}, bool, error) {
+	gvk = s.resolveAlias(gvk)
+
+	s.mu.RLock()
+	store, storedGvk := s.storeFor(gvk)
+	var (
+		storeKey = objectKeyToStoreKey(key)
+		found    = true
+		err      error
+	)
+	if store != nil {
+		storeKey, found, err = s.objectStoreKey(storedGvk, store, key)
+	}
+	s.mu.RUnlock()
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	return s.GetByKey(gvk, storeKey)
+}
+
+// objectStoreKey returns the key under which the object of the given namespace and name is
+// stored in the given store of the given GVK, and false if there is none. The objects of
+// the GVKs registered by RegisterKindWithKeyFunc are looked up through the metadata.name
+// index, and an error is returned if several of them share the namespace and name. The
+// lock must be held by the caller.
+func (s *CacheStores) objectStoreKey(gvk schema.GroupVersionKind, store cache.Indexer, key client.ObjectKey) (string, bool, error) {
+	keyFunc := s.keyFuncs[gvk]
+	if keyFunc == nil {
+		return objectKeyToStoreKey(key), true, nil
+	}
+
+	items, err := store.ByIndex(s.fieldIdxName(nameField), keyToNamespacedKey(key.Namespace, key.Name))
+	if err != nil {
+		return "", false, err
+	}
+
+	var keys []string
+	for _, item := range items {
+		meta, err := apimeta.Accessor(item)
+		if err != nil {
+			return "", false, err
+		}
+		// the all-namespaces key of a name also holds the namespaced objects.
+		if meta.GetNamespace() != key.Namespace {
+			continue
+		}
+		storeKey, err := keyFunc(item)
+		if err != nil {
+			return "", false, err
+		}
+		keys = append(keys, storeKey)
+	}
+
+	switch len(keys) {
+	case 0:
+		return "", false, nil
+	case 1:
+		return keys[0], true, nil
+	default:
+		return "", false, fmt.Errorf("%d %s objects are named %s, they can only be told apart by their keys", len(keys), gvk.Kind, key)
+	}
+}
+
+// groupResource guesses the resource of the given GVK, which is good enough for reporting
+// errors when no RESTMapper is available.
+func groupResource(gvk schema.GroupVersionKind) schema.GroupResource {
+	gvr, _ := apimeta.UnsafeGuessKindToResource(gvk)
+	return gvr.GroupResource()
+}
+
+// copyInto sets out to a deep copy of the given cached item.
+func copyInto(item interface{}, out client.Object, gvk schema.GroupVersionKind) error {
+	obj, isObj := item.(runtime.Object)
+	if !isObj {
+		return fmt.Errorf("cache contained %T, which is not an Object", item)
+	}
+
+	obj = obj.DeepCopyObject()
+	outVal := reflect.ValueOf(out)
+	objVal := reflect.ValueOf(obj)
+	if !objVal.Type().AssignableTo(outVal.Type()) {
+		return fmt.Errorf("cache had type %s, but %s was asked for", objVal.Type(), outVal.Type())
+	}
+	reflect.Indirect(outVal).Set(reflect.Indirect(objVal))
+	out.GetObjectKind().SetGroupVersionKind(gvk)
+
+	return nil
+}
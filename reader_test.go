@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// newCustomKeyedCache returns a cache whose deployments are keyed by their "id" annotation.
+func newCustomKeyedCache(t testing.TB) *CacheStores {
+	t.Helper()
+
+	c, err := New(newTestScheme())
+	if err != nil {
+		t.Fatalf("failed to create the cache: %v", err)
+	}
+	err = c.RegisterKindWithKeyFunc(&appsv1.Deployment{}, func(obj interface{}) (string, error) {
+		return obj.(client.Object).GetAnnotations()["id"], nil
+	})
+	if err != nil {
+		t.Fatalf("failed to register deployments: %v", err)
+	}
+
+	return c
+}
+
+func withID(obj *appsv1.Deployment, id string) *appsv1.Deployment {
+	obj.Annotations = map[string]string{"id": id}
+	return obj
+}
+
+func TestCustomKeyedLookupsByObjectKey(t *testing.T) {
+	key := client.ObjectKey{Namespace: "default", Name: "web"}
+	gvk := appsv1.SchemeGroupVersion.WithKind("Deployment")
+
+	lookups := []struct {
+		name string
+		get  func(c *CacheStores) (bool, error)
+	}{
+		{name: "reader", get: func(c *CacheStores) (bool, error) {
+			err := c.Reader().Get(context.Background(), key, &appsv1.Deployment{})
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return err == nil, err
+		}},
+		{name: "get into", get: func(c *CacheStores) (bool, error) {
+			err := c.GetInto(key, &appsv1.Deployment{})
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return err == nil, err
+		}},
+		{name: "typed cache", get: func(c *CacheStores) (bool, error) {
+			typed, err := NewTypedCache[*appsv1.Deployment](c)
+			if err != nil {
+				return false, err
+			}
+			_, exists, err := typed.Get(key)
+			return exists, err
+		}},
+	}
+	for _, lookup := range lookups {
+		t.Run(lookup.name, func(t *testing.T) {
+			c := newCustomKeyedCache(t)
+			mustAdd(t, c,
+				withID(deployment("default", "web", nil), "1"),
+				withID(deployment("other", "web", nil), "2"),
+			)
+
+			if exists, err := lookup.get(c); err != nil || !exists {
+				t.Fatalf("failed to find the deployment: exists %v, err %v", exists, err)
+			}
+
+			if err := c.DeleteByKey(gvk, key); err != nil {
+				t.Fatalf("failed to delete the deployment: %v", err)
+			}
+			if exists, err := lookup.get(c); err != nil || exists {
+				t.Errorf("found the deleted deployment: exists %v, err %v", exists, err)
+			}
+			if keys, _ := c.ListKeys(&appsv1.Deployment{}); len(keys) != 1 || keys[0] != "2" {
+				t.Errorf("got keys %v after the deletion, want [2]", keys)
+			}
+
+			// objects sharing a namespace and a name can not be told apart.
+			mustAdd(t, c,
+				withID(deployment("default", "web", nil), "3"),
+				withID(deployment("default", "web", nil), "4"),
+			)
+			if _, err := lookup.get(c); err == nil {
+				t.Error("expected an error for ambiguous objects")
+			}
+			if err := c.DeleteByKey(gvk, key); err == nil {
+				t.Error("expected an error deleting ambiguous objects")
+			}
+		})
+	}
+}
+
+func TestCustomKeyedPages(t *testing.T) {
+	c := newCustomKeyedCache(t)
+	// the first two objects share their namespace and name.
+	mustAdd(t, c,
+		withID(deployment("default", "web", nil), "3"),
+		withID(deployment("default", "web", nil), "1"),
+		withID(deployment("default", "api", nil), "2"),
+	)
+
+	var ids []string
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("the list did not end after %d pages", pages)
+		}
+		list := &appsv1.DeploymentList{}
+		if err := c.List(list, client.Limit(1), client.Continue(token)); err != nil {
+			t.Fatalf("failed to list: %v", err)
+		}
+		for i := range list.Items {
+			ids = append(ids, list.Items[i].Annotations["id"])
+		}
+		if list.Continue == "" {
+			break
+		}
+		token = list.Continue
+	}
+
+	if want := []string{"1", "2", "3"}; !slices.Equal(ids, want) {
+		t.Errorf("got ids %v, want %v", ids, want)
+	}
+}
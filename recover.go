@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// checkIndexable runs the given index functions on the given objects, and returns an error
+// if one of them fails or panics. client-go panics when an index function fails while it
+// updates its indexes, leaving the store half updated, so the objects are checked before
+// the store is mutated.
+func (s *CacheStores) checkIndexable(indexers cache.Indexers, objs ...interface{}) error {
+	for name, indexFunc := range indexers {
+		for _, obj := range objs {
+			if _, err := s.callIndexFunc(name, indexFunc, obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// callIndexFunc returns the values computed by the given index function for the given
+// object, converting its panic into an error.
+func (s *CacheStores) callIndexFunc(name string, indexFunc cache.IndexFunc, obj interface{}) (vals []string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("index function of %s panicked on %s: %v", name, objectDescription(obj), r)
+			s.log().Error(err, "recovered from a panicking index function", "stack", string(debug.Stack()))
+		}
+	}()
+
+	vals, err = indexFunc(obj)
+	if err != nil {
+		return nil, fmt.Errorf("index function of %s failed on %s: %w", name, objectDescription(obj), err)
+	}
+
+	return vals, nil
+}
+
+// callFilter calls the given filter, converting its panic into an error.
+func (s *CacheStores) callFilter(filter FilterFunc, obj client.Object) (matches bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("filter panicked on %s: %v", objectDescription(obj), r)
+			s.log().Error(err, "recovered from a panicking filter", "stack", string(debug.Stack()))
+		}
+	}()
+
+	return filter(obj), nil
+}
+
+func objectDescription(obj interface{}) string {
+	if o, ok := obj.(client.Object); ok {
+		return fmt.Sprintf("%T %s", o, client.ObjectKeyFromObject(o))
+	}
+	return fmt.Sprintf("%T", obj)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// panickingIndex panics on the deployments without labels, as a nil map dereference would.
+func panickingIndex(o client.Object) []string {
+	if o.GetLabels() == nil {
+		panic("no labels")
+	}
+	return []string{o.GetLabels()["app"]}
+}
+
+func TestPanickingIndexerFunc(t *testing.T) {
+	t.Run("on cached objects", func(t *testing.T) {
+		c := newTestCache(t)
+		mustAdd(t, c, deployment("default", "bare", nil))
+
+		if err := c.IndexField(&appsv1.Deployment{}, "app", panickingIndex); err == nil {
+			t.Fatal("expected an error indexing a field whose function panics")
+		}
+		if vals, err := c.IndexValues(&appsv1.Deployment{}, "app"); err == nil {
+			t.Errorf("the index was registered with the values %v", vals)
+		}
+	})
+
+	t.Run("on added objects", func(t *testing.T) {
+		c := newTestCache(t)
+		if err := c.IndexField(&appsv1.Deployment{}, "app", panickingIndex); err != nil {
+			t.Fatalf("failed to index the field: %v", err)
+		}
+		mustAdd(t, c, deployment("default", "web", map[string]string{"app": "web"}))
+
+		if err := c.Add(deployment("default", "bare", nil)); err == nil {
+			t.Fatal("expected an error adding an object the index function panics on")
+		}
+		// the cache is left consistent.
+		if keys := listDeploymentKeys(t, c); len(keys) != 1 || keys[0] != "default/web" {
+			t.Errorf("got deployments %v, want [default/web]", keys)
+		}
+		if keys := listDeploymentKeys(t, c, client.MatchingFields{"app": "web"}); len(keys) != 1 {
+			t.Errorf("got deployments %v by index, want [default/web]", keys)
+		}
+	})
+}
+
+func TestPanickingFilterFunc(t *testing.T) {
+	c := newTestCache(t)
+	mustAdd(t, c, deployment("default", "bare", nil))
+
+	filter := FilterFunc(func(o client.Object) bool {
+		return o.GetLabels()["app"] == "web" || panickingIndex(o) == nil
+	})
+	if err := c.List(&appsv1.DeploymentList{}, filter); err == nil {
+		t.Error("expected an error listing with a panicking filter")
+	}
+}
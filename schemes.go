@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// AddScheme appends a scheme to the ones resolving the GVKs of objects, which makes it
+// possible to cache types registered in independent schemes, e.g. by plugins. Schemes
+// are tried in the order they were added, the scheme given to New first.
+func (s *CacheStores) AddScheme(scheme *runtime.Scheme) error {
+	if scheme == nil {
+		return errors.New("cannot add a nil scheme")
+	}
+
+	s.schemesMu.Lock()
+	defer s.schemesMu.Unlock()
+
+	// the slice is copied so that readers can keep iterating over the previous one.
+	schemes := make([]*runtime.Scheme, 0, len(s.schemes)+1)
+	schemes = append(schemes, s.schemes...)
+	s.schemes = append(schemes, scheme)
+
+	return nil
+}
+
+func (s *CacheStores) schemeList() []*runtime.Scheme {
+	s.schemesMu.RLock()
+	defer s.schemesMu.RUnlock()
+
+	return s.schemes
+}
+
+// gvkFor returns the GVK of the given object from the first scheme resolving its type.
+func (s *CacheStores) gvkFor(obj runtime.Object) (*schema.GroupVersionKind, error) {
+	schemes := s.schemeList()
+	if len(schemes) == 1 {
+		return gvkFromObject(obj, schemes[0])
+	}
+
+	var errs []error
+	for _, scheme := range schemes {
+		gvk, err := gvkFromObject(obj, scheme)
+		if err == nil {
+			return gvk, nil
+		}
+		errs = append(errs, err)
+	}
+
+	return nil, utilerrors.NewAggregate(errs)
+}
+
+// schemeFor returns the first scheme recognizing the given GVK, or the scheme given to New
+// if none does.
+func (s *CacheStores) schemeFor(gvk schema.GroupVersionKind) *runtime.Scheme {
+	schemes := s.schemeList()
+	for _, scheme := range schemes {
+		if scheme.Recognizes(gvk) {
+			return scheme
+		}
+	}
+
+	return schemes[0]
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestTypeMetaOfAnotherVersion(t *testing.T) {
+	scheme := newTestScheme()
+	// the Deployment type is registered under a second version of its group.
+	alt := schema.GroupVersion{Group: "apps", Version: "v1alt"}.WithKind("Deployment")
+	scheme.AddKnownTypeWithName(alt, &appsv1.Deployment{})
+
+	c, err := New(scheme)
+	if err != nil {
+		t.Fatalf("failed to create the cache: %v", err)
+	}
+
+	obj := deployment("default", "web", nil)
+	obj.APIVersion, obj.Kind = alt.GroupVersion().String(), alt.Kind
+	mustAdd(t, c, obj)
+
+	counts := c.CountAll()
+	if counts[alt] != 1 || counts[appsv1.SchemeGroupVersion.WithKind("Deployment")] != 0 {
+		t.Fatalf("the deployment is not stored under %s: %v", alt, counts)
+	}
+
+	item, exists, err := c.Get(obj)
+	if err != nil || !exists {
+		t.Fatalf("failed to get the deployment: exists %v, err %v", exists, err)
+	}
+	if gvk := item.(*appsv1.Deployment).GroupVersionKind(); gvk != alt {
+		t.Errorf("got GVK %s, want %s", gvk, alt)
+	}
+	// no store was registered for the default version.
+	v1 := deployment("default", "web", nil)
+	v1.APIVersion, v1.Kind = "apps/v1", "Deployment"
+	if _, _, err := c.Get(v1); !errors.Is(err, ErrGvkNotFound) {
+		t.Errorf("got error %v under apps/v1, want ErrGvkNotFound", err)
+	}
+
+	// a TypeMeta the type is not registered under is rejected.
+	bad := deployment("default", "bad", nil)
+	bad.APIVersion, bad.Kind = "apps/v1", "StatefulSet"
+	if err := c.Add(bad); err == nil {
+		t.Error("expected an error adding a deployment with the TypeMeta of a StatefulSet")
+	}
+
+	if err := c.Delete(obj); err != nil {
+		t.Fatalf("failed to delete the deployment: %v", err)
+	}
+	if counts := c.CountAll(); counts[alt] != 0 {
+		t.Errorf("the deployment was not deleted: %v", counts)
+	}
+}
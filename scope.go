@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SetRESTMapper sets the RESTMapper telling which GVKs are cluster-scoped. List rejects
+// namespaced queries on cluster-scoped GVKs, whose objects are indexed under the
+// all-namespaces keys only. GVKs unknown to the mapper, or all of them if no mapper is set,
+// are assumed to be namespaced when their objects have a namespace.
+//
+// The indexes registered before the mapper is set keep telling the scope of objects by
+// their namespace, use WithRESTMapper to have the stores created by New indexed by scope.
+func (s *CacheStores) SetRESTMapper(mapper apimeta.RESTMapper) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.restMapper = mapper
+}
+
+// isClusterScoped reports whether the RESTMapper knows the given GVK as cluster-scoped.
+// The lock must be held by the caller.
+func (s *CacheStores) isClusterScoped(gvk schema.GroupVersionKind) (bool, error) {
+	if s.restMapper == nil {
+		return false, nil
+	}
+
+	mapping, err := s.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if apimeta.IsNoMatchError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return mapping.Scope.Name() == apimeta.RESTScopeNameRoot, nil
+}
+
+// indexFuncFor returns the index function of the values extracted from the objects of the
+// given GVK: cluster-scoped objects are indexed under their all-namespaces key only, even
+// if they carry a namespace, and the other ones by namespacedIndexFunc. The lock must be
+// held by the caller.
+func (s *CacheStores) indexFuncFor(gvk schema.GroupVersionKind, extractValue client.IndexerFunc) cache.IndexFunc {
+	// errors of the mapper fall back to telling the scope by the namespace of the objects.
+	if clusterScoped, err := s.isClusterScoped(gvk); err == nil && clusterScoped {
+		return clusterIndexFunc(extractValue)
+	}
+	return namespacedIndexFunc(extractValue)
+}
+
+// clusterIndexFunc returns an index function indexing the values extracted from an object
+// under their all-namespaces key only.
+func clusterIndexFunc(extractValue client.IndexerFunc) cache.IndexFunc {
+	return func(objRaw interface{}) ([]string, error) {
+		obj, isObj := objRaw.(client.Object)
+		if !isObj {
+			return nil, fmt.Errorf("object of type %T is not an Object", objRaw)
+		}
+
+		rawVals := extractValue(obj)
+		vals := make([]string, len(rawVals))
+		for i, rawVal := range rawVals {
+			vals[i] = keyToNamespacedKey("", rawVal)
+		}
+
+		return vals, nil
+	}
+}
+
+// validateNamespace returns an error if the given namespace is set for a cluster-scoped GVK.
+// The lock must be held by the caller.
+func (s *CacheStores) validateNamespace(gvk schema.GroupVersionKind, namespace string) error {
+	if namespace == "" {
+		return nil
+	}
+
+	clusterScoped, err := s.isClusterScoped(gvk)
+	if err != nil {
+		return err
+	}
+	if clusterScoped {
+		return fmt.Errorf("%s is cluster-scoped and can not be listed in namespace %q", gvk.Kind, namespace)
+	}
+
+	return nil
+}
+
+// resourceFor returns the GroupResource of the given GVK from the RESTMapper, or guessed
+// from the kind if no mapper is set or if it does not know the GVK.
+func (s *CacheStores) resourceFor(gvk schema.GroupVersionKind) schema.GroupResource {
+	s.mu.RLock()
+	mapper := s.restMapper
+	s.mu.RUnlock()
+
+	if mapper != nil {
+		if mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err == nil {
+			return mapping.Resource.GroupResource()
+		}
+	}
+
+	return groupResource(gvk)
+}
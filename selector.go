@@ -0,0 +1,94 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// FieldIn returns a field selector matching objects whose field has one of the given values.
+// The values must not contain commas.
+//
+// The selector is meant to be passed to List through client.MatchingFieldsSelector.
+func FieldIn(field string, values ...string) fields.Selector {
+	return &fieldSetSelector{field: field, operator: selection.In, values: values}
+}
+
+// FieldNotIn returns a field selector matching objects whose field has none of the given values.
+// The values must not contain commas.
+func FieldNotIn(field string, values ...string) fields.Selector {
+	return &fieldSetSelector{field: field, operator: selection.NotIn, values: values}
+}
+
+// FieldExists returns a field selector matching objects for which the field index of the
+// given field holds at least one non-empty value.
+func FieldExists(field string) fields.Selector {
+	return &fieldSetSelector{field: field, operator: selection.Exists}
+}
+
+// fieldSetSelector implements fields.Selector for the set-based In, NotIn and Exists
+// operators, which are not supported by the field selectors of apimachinery.
+type fieldSetSelector struct {
+	field    string
+	operator selection.Operator
+	values   []string
+}
+
+func (f *fieldSetSelector) Matches(ls fields.Fields) bool {
+	if f.operator == selection.Exists {
+		return ls.Has(f.field) && ls.Get(f.field) != ""
+	}
+
+	found := ls.Has(f.field) && slices.Contains(f.values, ls.Get(f.field))
+	if f.operator == selection.NotIn {
+		return !found
+	}
+	return found
+}
+
+func (f *fieldSetSelector) Empty() bool {
+	return false
+}
+
+func (f *fieldSetSelector) RequiresExactMatch(string) (string, bool) {
+	return "", false
+}
+
+func (f *fieldSetSelector) Transform(fn fields.TransformFunc) (fields.Selector, error) {
+	field := f.field
+	values := make([]string, len(f.values))
+	for i, v := range f.values {
+		var err error
+		field, values[i], err = fn(f.field, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &fieldSetSelector{field: field, operator: f.operator, values: values}, nil
+}
+
+func (f *fieldSetSelector) Requirements() fields.Requirements {
+	return fields.Requirements{{
+		Operator: f.operator,
+		Field:    f.field,
+		Value:    strings.Join(f.values, ","),
+	}}
+}
+
+func (f *fieldSetSelector) String() string {
+	if f.operator == selection.Exists {
+		return f.field
+	}
+	return f.field + " " + string(f.operator) + " (" + strings.Join(f.values, ",") + ")"
+}
+
+func (f *fieldSetSelector) DeepCopySelector() fields.Selector {
+	if f == nil {
+		return nil
+	}
+	values := make([]string, len(f.values))
+	copy(values, f.values)
+	return &fieldSetSelector{field: f.field, operator: f.operator, values: values}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IndexStats describes the store of a GVK.
+type IndexStats struct {
+	// Objects is the number of cached objects.
+	Objects int
+	// Indexes maps the name of every client-go index of the store to the number of
+	// distinct keys it holds. Field indexes are named after the prefix set by
+	// WithIndexPrefix, and the values of namespaced objects are usually counted twice,
+	// under their namespace and across all namespaces, see IndexNamespacedOnly.
+	Indexes map[string]int
+}
+
+// Stats returns the number of objects and the cardinality of the indexes of the store of
+// the GVK of the given object, e.g. to spot an index holding too many distinct values. It
+// returns ErrGvkNotFound if the GVK is not registered.
+func (s *CacheStores) Stats(obj client.Object) (IndexStats, error) {
+	if obj == nil {
+		return IndexStats{}, ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return IndexStats{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		return IndexStats{}, ErrGvkNotFound
+	}
+
+	indexers := store.GetIndexers()
+	stats := IndexStats{
+		Objects: len(store.ListKeys()),
+		Indexes: make(map[string]int, len(indexers)),
+	}
+	for name := range indexers {
+		stats.Indexes[name] = len(store.ListIndexFuncValues(name))
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EnableStatusSubresource makes the objects sharing the GVK of the given object behave as
+// if their kind had a /status subresource: Update keeps the cached status, and the status
+// is only written by UpdateStatus, which keeps the rest of the cached object.
+func (s *CacheStores) EnableStatusSubresource(obj client.Object) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.statusSubresources == nil {
+		s.statusSubresources = make(map[schema.GroupVersionKind]bool)
+	}
+	s.statusSubresources[*gvk] = true
+
+	return nil
+}
+
+// UpdateStatus replaces the status of a cached object of a GVK with a status subresource,
+// see EnableStatusSubresource, with the status of the given object. It returns ErrNotFound
+// if the object is not cached.
+//
+// The status has a resource version of its own, see StatusResourceVersion, incremented by
+// UpdateStatus, whereas the resource version of the object is left unchanged. Like Update,
+// UpdateStatus returns a Conflict API error if the object has a resource version that
+// differs from the cached one.
+func (s *CacheStores) UpdateStatus(obj client.Object) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		s.mu.Unlock()
+		return ErrNotFound
+	}
+	if !s.statusSubresources[*gvk] {
+		s.mu.Unlock()
+		return fmt.Errorf("%s has no status subresource", *gvk)
+	}
+
+	var stored, oldObj client.Object
+	old, exists, err := store.Get(obj)
+	if err == nil && !exists {
+		err = ErrNotFound
+	}
+	if err == nil {
+		var ok bool
+		if oldObj, ok = old.(client.Object); !ok {
+			err = fmt.Errorf("cache contained %T, which is not a client.Object", old)
+		}
+	}
+	if err == nil {
+		if rv := obj.GetResourceVersion(); rv != "" && rv != oldObj.GetResourceVersion() {
+			err = apierrors.NewConflict(groupResource(*gvk), obj.GetName(),
+				fmt.Errorf("the object has been modified; please apply your changes to the latest version and try again"))
+		}
+	}
+	if err == nil {
+		stored, err = s.withStatusOf(*gvk, oldObj, obj)
+	}
+	if err == nil {
+		stored, err = s.transformed(*gvk, stored)
+	}
+	if err == nil {
+		err = s.checkIndexable(store.GetIndexers(), stored)
+	}
+	if err == nil {
+		err = store.Update(stored)
+	}
+	if err == nil {
+		s.trackModified(*gvk, stored)
+		s.bumpStatusVersion(*gvk, stored)
+		s.bumpGeneration(*gvk)
+	}
+	handlers := s.reserveHandlers(*gvk)
+	defer releaseHandlers(handlers)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	s.observe().OnAdd(*gvk)
+	s.notifyUpdate(handlers, old, stored)
+
+	return nil
+}
+
+// StatusResourceVersion returns the resource version of the status of the given cached
+// object, incremented by every UpdateStatus. It returns ErrNotFound if the object is not
+// cached.
+func (s *CacheStores) StatusResourceVersion(obj client.Object) (string, error) {
+	if obj == nil {
+		return "", ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	store := s.storesByGvk[*gvk]
+	if store == nil {
+		return "", ErrNotFound
+	}
+	key, err := s.keyFunc(*gvk)(obj)
+	if err != nil {
+		return "", err
+	}
+	if _, exists, err := store.GetByKey(key); err != nil || !exists {
+		return "", ErrNotFound
+	}
+
+	return strconv.FormatUint(s.statusVersions[*gvk][key], 10), nil
+}
+
+// keepStatus returns a copy of obj holding the status of the cached old object if the GVK
+// has a status subresource, and obj itself otherwise. The lock must be held by the caller.
+func (s *CacheStores) keepStatus(gvk schema.GroupVersionKind, old interface{}, obj client.Object) (client.Object, error) {
+	if !s.statusSubresources[gvk] {
+		return obj, nil
+	}
+
+	oldObj, ok := old.(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("cache contained %T, which is not a client.Object", old)
+	}
+
+	return s.withStatusOf(gvk, obj, oldObj)
+}
+
+// withStatusOf returns a copy of obj holding the status of statusObj, in the representation
+// of obj.
+func (s *CacheStores) withStatusOf(gvk schema.GroupVersionKind, obj, statusObj client.Object) (client.Object, error) {
+	content, err := objectContent(obj)
+	if err != nil {
+		return nil, err
+	}
+	statusContent, err := objectContent(statusObj)
+	if err != nil {
+		return nil, err
+	}
+
+	if status, ok := statusContent["status"]; ok {
+		content["status"] = status
+	} else {
+		delete(content, "status")
+	}
+
+	var merged runtime.Object
+	switch representationOf(obj) {
+	case unstructuredObject:
+		merged = &unstructured.Unstructured{Object: content}
+	case partialMetadata:
+		// metadata-only objects have no status.
+		merged = obj.DeepCopyObject()
+	default:
+		merged, err = contentToStructured(content, s.schemeFor(gvk), gvk)
+		if err != nil {
+			return nil, err
+		}
+	}
+	merged.GetObjectKind().SetGroupVersionKind(gvk)
+
+	mergedObj, ok := merged.(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a client.Object", merged)
+	}
+
+	return mergedObj, nil
+}
+
+// bumpStatusVersion increments the resource version of the status of the given object. The
+// write lock must be held by the caller.
+func (s *CacheStores) bumpStatusVersion(gvk schema.GroupVersionKind, obj interface{}) {
+	key, err := s.keyFunc(gvk)(obj)
+	if err != nil {
+		return
+	}
+
+	if s.statusVersions == nil {
+		s.statusVersions = make(map[schema.GroupVersionKind]map[string]uint64)
+	}
+	if s.statusVersions[gvk] == nil {
+		s.statusVersions[gvk] = make(map[string]uint64)
+	}
+	s.statusVersions[gvk][key]++
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestUpdateStatus(t *testing.T) {
+	c := newTestCache(t)
+	if err := c.EnableStatusSubresource(&appsv1.Deployment{}); err != nil {
+		t.Fatalf("failed to enable the status subresource: %v", err)
+	}
+	mustAdd(t, c, deployment("default", "web", map[string]string{"app": "web"}))
+
+	obj := deployment("default", "web", map[string]string{"app": "ignored"})
+	obj.Status.Replicas = 3
+	if err := c.UpdateStatus(obj); err != nil {
+		t.Fatalf("failed to update the status: %v", err)
+	}
+
+	item, exists, err := c.Get(deployment("default", "web", nil))
+	if err != nil || !exists {
+		t.Fatalf("failed to get: exists %v, err %v", exists, err)
+	}
+	got := item.(*appsv1.Deployment)
+	if got.Status.Replicas != 3 {
+		t.Errorf("got %d replicas, want 3", got.Status.Replicas)
+	}
+	if app := got.Labels["app"]; app != "web" {
+		t.Errorf("got app label %q, want the unchanged %q", app, "web")
+	}
+}
+
+func TestUpdateStatusOfNonObject(t *testing.T) {
+	c := newTestCache(t)
+	if err := c.EnableStatusSubresource(&appsv1.Deployment{}); err != nil {
+		t.Fatalf("failed to enable the status subresource: %v", err)
+	}
+	// the store holds a value which is not a client.Object under the key of the deployment.
+	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := store.Add(cache.ExplicitKey("default/web")); err != nil {
+		t.Fatalf("failed to add to the store: %v", err)
+	}
+	c.mu.Lock()
+	c.storesByGvk[appsv1.SchemeGroupVersion.WithKind("Deployment")] = store
+	c.mu.Unlock()
+
+	if err := c.UpdateStatus(deployment("default", "web", nil)); err == nil {
+		t.Error("expected an error updating the status of a value which is not an object")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MarkSynced marks the GVK of the given object as synced, e.g. once the initial list
+// of an informer populating the cache has been added.
+func (s *CacheStores) MarkSynced(obj client.Object) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := s.syncedChan(*gvk)
+	select {
+	case <-ch:
+	default:
+		close(ch)
+	}
+
+	return nil
+}
+
+// HasSynced reports whether the GVK of the given object was marked as synced.
+func (s *CacheStores) HasSynced(obj client.Object) bool {
+	if obj == nil {
+		return false
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return false
+	}
+
+	s.mu.RLock()
+	ch := s.synced[*gvk]
+	s.mu.RUnlock()
+	if ch == nil {
+		return false
+	}
+
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitForSync blocks until the GVKs of all the given objects are marked as synced, in which
+// case it returns true, or until ctx is done, in which case it returns false.
+func (s *CacheStores) WaitForSync(ctx context.Context, objs ...client.Object) bool {
+	chans := make([]chan struct{}, 0, len(objs))
+
+	s.mu.Lock()
+	for _, obj := range objs {
+		if obj == nil {
+			s.mu.Unlock()
+			return false
+		}
+
+		gvk, err := s.gvkFor(obj)
+		if err != nil {
+			s.mu.Unlock()
+			return false
+		}
+		chans = append(chans, s.syncedChan(*gvk))
+	}
+	s.mu.Unlock()
+
+	for _, ch := range chans {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	return true
+}
+
+// syncedChan returns the channel closed once the given GVK is synced. It must be
+// called with the write lock held.
+func (s *CacheStores) syncedChan(gvk schema.GroupVersionKind) chan struct{} {
+	if s.synced == nil {
+		s.synced = make(map[schema.GroupVersionKind]chan struct{})
+	}
+
+	ch := s.synced[gvk]
+	if ch == nil {
+		ch = make(chan struct{})
+		s.synced[gvk] = ch
+	}
+
+	return ch
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LastModified returns when the cached object sharing the key of the given object was last
+// added or updated, and whether it is cached.
+func (s *CacheStores) LastModified(obj client.Object) (time.Time, bool, error) {
+	if obj == nil {
+		return time.Time{}, false, ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.storesByGvk[*gvk] == nil {
+		return time.Time{}, false, ErrGvkNotFound
+	}
+
+	key, err := s.keyFunc(*gvk)(obj)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	modified, ok := s.modified[*gvk][key]
+	return modified, ok, nil
+}
+
+// trackModified records that the given object of the given GVK was just written. The write
+// lock must be held by the caller.
+func (s *CacheStores) trackModified(gvk schema.GroupVersionKind, obj interface{}) {
+	key, err := s.keyFunc(gvk)(obj)
+	if err != nil {
+		return
+	}
+
+	if s.modified == nil {
+		s.modified = make(map[schema.GroupVersionKind]map[string]time.Time)
+	}
+	if s.modified[gvk] == nil {
+		s.modified[gvk] = make(map[string]time.Time)
+	}
+	s.modified[gvk][key] = time.Now()
+}
+
+// trackDeleted forgets a deleted object of the given GVK. The write lock must be held by
+// the caller.
+func (s *CacheStores) trackDeleted(gvk schema.GroupVersionKind, obj interface{}) {
+	key, err := s.keyFunc(gvk)(obj)
+	if err != nil {
+		return
+	}
+
+	delete(s.modified[gvk], key)
+	delete(s.statusVersions[gvk], key)
+	if limit := s.limits[gvk]; limit != nil {
+		limit.untrack(key)
+	}
+}
+
+// trackCleared forgets every object of the given GVK. The write lock must be held by the
+// caller.
+func (s *CacheStores) trackCleared(gvk schema.GroupVersionKind) {
+	delete(s.modified, gvk)
+	delete(s.statusVersions, gvk)
+	if limit := s.limits[gvk]; limit != nil {
+		limit.order = nil
+		limit.seqs = make(map[string]uint64)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SetTransform sets the transform applied to the objects of every GVK before they are
+// stored, e.g. to drop managed fields and save memory. GVKs with a transform set by
+// SetKindTransform use that one instead. A nil transform disables it.
+//
+// The transform is given a copy of the object, which it may modify or replace, but it
+// must not change its namespace and name since the object is keyed by the result.
+func (s *CacheStores) SetTransform(transform cache.TransformFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.transform = transform
+}
+
+// SetKindTransform sets the transform applied to the objects sharing the GVK of the given
+// object before they are stored, in place of the one set by SetTransform. A nil transform
+// falls back to the latter.
+func (s *CacheStores) SetKindTransform(obj client.Object, transform cache.TransformFunc) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if transform == nil {
+		delete(s.transforms, *gvk)
+		return nil
+	}
+	if s.transforms == nil {
+		s.transforms = make(map[schema.GroupVersionKind]cache.TransformFunc)
+	}
+	s.transforms[*gvk] = transform
+
+	return nil
+}
+
+// transformed returns the result of the transform of the given GVK applied to obj, or obj
+// itself if there is no transform. The lock must be held by the caller.
+func (s *CacheStores) transformed(gvk schema.GroupVersionKind, obj client.Object) (client.Object, error) {
+	transform := s.transforms[gvk]
+	if transform == nil {
+		transform = s.transform
+	}
+	if transform == nil {
+		return obj, nil
+	}
+
+	out, err := transform(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform %s %s: %w", gvk.Kind, client.ObjectKeyFromObject(obj), err)
+	}
+
+	transformed, ok := out.(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("transform of %s returned %T, which is not a client.Object", gvk.Kind, out)
+	}
+	transformed.GetObjectKind().SetGroupVersionKind(gvk)
+
+	return transformed, nil
+}
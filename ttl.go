@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// StartTTLReaper starts a goroutine deleting, every interval, the objects sharing the GVK of
+// the given object which were not modified for longer than ttl. Event handlers are notified
+// through OnDelete about each reaped object. The reaper stops once ctx is done or the cache
+// is closed, see Close.
+func (s *CacheStores) StartTTLReaper(ctx context.Context, obj client.Object, ttl time.Duration, interval time.Duration) error {
+	if obj == nil {
+		return ErrNilObj
+	}
+	if ttl <= 0 || interval <= 0 {
+		return fmt.Errorf("invalid TTL reaper durations: ttl %s, interval %s", ttl, interval)
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r := &reaper{cancel: cancel, done: make(chan struct{})}
+	s.reapersMu.Lock()
+	s.reapers = append(s.reapers, r)
+	s.reapersMu.Unlock()
+
+	go func() {
+		defer s.removeReaper(r)
+		defer close(r.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.reapExpired(*gvk, now.Add(-ttl))
+			}
+		}
+	}()
+
+	return nil
+}
+
+// reaper is the goroutine started by StartTTLReaper.
+type reaper struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// stopReapers stops the running reapers and waits for them to return.
+func (s *CacheStores) stopReapers() {
+	s.reapersMu.Lock()
+	reapers := slices.Clone(s.reapers)
+	s.reapersMu.Unlock()
+
+	for _, r := range reapers {
+		r.cancel()
+	}
+	for _, r := range reapers {
+		<-r.done
+	}
+}
+
+// removeReaper forgets the given reaper once it returned.
+func (s *CacheStores) removeReaper(r *reaper) {
+	s.reapersMu.Lock()
+	defer s.reapersMu.Unlock()
+
+	s.reapers = slices.DeleteFunc(s.reapers, func(other *reaper) bool {
+		return other == r
+	})
+}
+
+// reapExpired deletes the objects of the given GVK last modified before the given deadline.
+func (s *CacheStores) reapExpired(gvk schema.GroupVersionKind, deadline time.Time) {
+	s.mu.Lock()
+	store := s.storesByGvk[gvk]
+	if store == nil {
+		s.mu.Unlock()
+		return
+	}
+
+	var reaped []interface{}
+	for key, modified := range s.modified[gvk] {
+		if !modified.Before(deadline) {
+			continue
+		}
+
+		obj, exists, err := store.GetByKey(key)
+		if err != nil {
+			continue
+		}
+		if !exists {
+			delete(s.modified[gvk], key)
+			continue
+		}
+		if err := store.Delete(obj); err != nil {
+			continue
+		}
+		s.trackDeleted(gvk, obj)
+		reaped = append(reaped, obj)
+	}
+	if len(reaped) > 0 {
+		s.bumpGeneration(gvk)
+	}
+	handlers := s.reserveHandlers(gvk)
+	defer releaseHandlers(handlers)
+	s.mu.Unlock()
+
+	s.notifyEvicted(gvk, handlers, reaped)
+}
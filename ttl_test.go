@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+func TestTTLReaperDeletesExpiredObjects(t *testing.T) {
+	c := newTestCache(t)
+	h := &recordingHandler{}
+	if err := c.AddEventHandler(&appsv1.Deployment{}, h); err != nil {
+		t.Fatalf("failed to add the event handler: %v", err)
+	}
+	mustAdd(t, c, deployment("default", "web", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := c.StartTTLReaper(ctx, &appsv1.Deployment{}, 10*time.Millisecond, time.Millisecond); err != nil {
+		t.Fatalf("failed to start the reaper: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(listDeploymentKeys(t, c)) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the expired deployment was not reaped")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	want := []string{"add default/web", "delete default/web"}
+	if got := h.recorded(); !slices.Equal(got, want) {
+		t.Errorf("got events %v, want %v", got, want)
+	}
+}
+
+func TestTTLReaperKeepsUpdatedObjects(t *testing.T) {
+	c := newTestCache(t)
+	gvk := appsv1.SchemeGroupVersion.WithKind("Deployment")
+	mustAdd(t, c, deployment("default", "a", nil), deployment("default", "b", nil))
+	time.Sleep(time.Millisecond)
+	expiry := time.Now()
+	// the update refreshes the modification time of a.
+	if err := c.Update(deployment("default", "a", map[string]string{"app": "a"})); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	c.reapExpired(gvk, expiry)
+	if got, want := listDeploymentKeys(t, c), []string{"default/a"}; !slices.Equal(got, want) {
+		t.Errorf("got %v cached, want %v", got, want)
+	}
+}
+
+func TestTTLReaperStopsOnClose(t *testing.T) {
+	c := newTestCache(t)
+	if err := c.StartTTLReaper(context.Background(), &appsv1.Deployment{}, time.Millisecond, time.Millisecond); err != nil {
+		t.Fatalf("failed to start the reaper: %v", err)
+	}
+	c.reapersMu.Lock()
+	reapers := slices.Clone(c.reapers)
+	c.reapersMu.Unlock()
+	if len(reapers) != 1 {
+		t.Fatalf("got %d reapers, want 1", len(reapers))
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	select {
+	case <-reapers[0].done:
+	default:
+		t.Fatal("the reaper is still running once the cache is closed")
+	}
+
+	mustAdd(t, c, deployment("default", "web", nil))
+	time.Sleep(20 * time.Millisecond)
+	if got, want := listDeploymentKeys(t, c), []string{"default/web"}; !slices.Equal(got, want) {
+		t.Errorf("got %v cached, want %v", got, want)
+	}
+}
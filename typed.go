@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TypedCache provides strongly-typed access to the objects of a single kind stored in
+// CacheStores, e.g. TypedCache[*appsv1.Deployment]. It shares the underlying stores, so
+// objects added through a TypedCache are visible through CacheStores and vice versa.
+type TypedCache[T client.Object] struct {
+	stores *CacheStores
+	gvk    schema.GroupVersionKind
+}
+
+// NewTypedCache returns a TypedCache for T backed by the given stores. The GVK of T is
+// resolved once from the schemes of the stores.
+func NewTypedCache[T client.Object](stores *CacheStores) (*TypedCache[T], error) {
+	if stores == nil {
+		return nil, fmt.Errorf("cannot create typed cache from nil stores")
+	}
+
+	var zero T
+	t := reflect.TypeOf(zero)
+	if t == nil || t.Kind() != reflect.Pointer {
+		return nil, fmt.Errorf("typed cache requires a pointer type, got %T", zero)
+	}
+
+	gvk, err := stores.gvkFor(reflect.New(t.Elem()).Interface().(T))
+	if err != nil {
+		return nil, err
+	}
+
+	return &TypedCache[T]{stores: stores, gvk: *gvk}, nil
+}
+
+// Get returns the object stored under the given key.
+func (c *TypedCache[T]) Get(key client.ObjectKey) (T, bool, error) {
+	var zero T
+
+	item, exists, err := c.stores.getByObjectKey(c.gvk, key)
+	if err != nil || !exists {
+		return zero, exists, err
+	}
+
+	obj, ok := item.(T)
+	if !ok {
+		return zero, false, fmt.Errorf("cache contained %T, expected %T", item, zero)
+	}
+
+	return obj, true, nil
+}
+
+// List returns the objects matching the given list options.
+func (c *TypedCache[T]) List(opts ...client.ListOption) ([]T, error) {
+	runtimeObjs, _, err := c.stores.list(context.Background(), c.gvk, true, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := make([]T, 0, len(runtimeObjs))
+	for _, item := range runtimeObjs {
+		obj, ok := item.(T)
+		if !ok {
+			var zero T
+			return nil, fmt.Errorf("cache contained %T, expected %T", item, zero)
+		}
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
+
+// Add adds the given object to the cache.
+func (c *TypedCache[T]) Add(obj T) error {
+	return c.stores.Add(obj)
+}
+
+// Delete deletes the given object from the cache.
+func (c *TypedCache[T]) Delete(obj T) error {
+	return c.stores.Delete(obj)
+}
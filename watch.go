@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultWatchBufferSize is the size of the channels returned by Watch unless
+// WatchBufferSize is given.
+const defaultWatchBufferSize = 100
+
+// EventType is the type of the change reported by an Event.
+type EventType string
+
+const (
+	Added    EventType = "ADDED"
+	Modified EventType = "MODIFIED"
+	Deleted  EventType = "DELETED"
+)
+
+// Event is a change of a cached object, delivered by Watch.
+type Event struct {
+	Type EventType
+	// Object is a copy of the added or updated object, or of the last state of the
+	// deleted object.
+	Object client.Object
+}
+
+type watchOptions struct {
+	bufferSize int
+	block      bool
+}
+
+// WatchOption configures the channel returned by Watch.
+type WatchOption func(*watchOptions)
+
+// WatchBufferSize sets the number of events buffered in the channel returned by Watch.
+func WatchBufferSize(size int) WatchOption {
+	return func(o *watchOptions) {
+		o.bufferSize = size
+	}
+}
+
+// WatchBlocking makes the cache wait for the receiver when the channel returned by Watch
+// is full, instead of dropping the event. As events are delivered synchronously, a slow
+// receiver then blocks the writers of the watched GVK.
+func WatchBlocking() WatchOption {
+	return func(o *watchOptions) {
+		o.block = true
+	}
+}
+
+// Watch returns a channel of the changes of the objects sharing the GVK of the given
+// object, and a function stopping the watch and closing the channel. By default, events
+// are dropped when the channel is full, see WatchBufferSize and WatchBlocking.
+func (s *CacheStores) Watch(obj client.Object, opts ...WatchOption) (<-chan Event, func(), error) {
+	if obj == nil {
+		return nil, nil, ErrNilObj
+	}
+
+	gvk, err := s.gvkFor(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	watchOpts := watchOptions{bufferSize: defaultWatchBufferSize}
+	for _, opt := range opts {
+		opt(&watchOpts)
+	}
+	if watchOpts.bufferSize < 0 {
+		return nil, nil, fmt.Errorf("invalid watch buffer size %d", watchOpts.bufferSize)
+	}
+
+	w := &watcher{
+		ch:    make(chan Event, watchOpts.bufferSize),
+		done:  make(chan struct{}),
+		block: watchOpts.block,
+	}
+	// unlike AddEventHandler, only the changes following the call are delivered.
+	s.addEventHandler(*gvk, w)
+
+	stop := func() {
+		s.removeEventHandler(*gvk, w)
+		w.stop()
+	}
+
+	return w.ch, stop, nil
+}
+
+// removeEventHandler unregisters the given handler from the given GVK. The handlers are
+// copied so that the slices already captured for pending notifications are left untouched.
+func (s *CacheStores) removeEventHandler(gvk schema.GroupVersionKind, handler cache.ResourceEventHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	handlers := make([]cache.ResourceEventHandler, 0, len(s.handlers[gvk]))
+	for _, h := range s.handlers[gvk] {
+		if h != handler {
+			handlers = append(handlers, h)
+		}
+	}
+	s.handlers[gvk] = handlers
+}
+
+// watcher is the cache.ResourceEventHandler forwarding events to the channel of a Watch.
+type watcher struct {
+	// mu guards ch from being closed while events are sent.
+	mu      sync.RWMutex
+	ch      chan Event
+	done    chan struct{}
+	once    sync.Once
+	stopped bool
+	block   bool
+}
+
+func (w *watcher) OnAdd(obj interface{}, _ bool) {
+	w.send(Added, obj)
+}
+
+func (w *watcher) OnUpdate(_, newObj interface{}) {
+	w.send(Modified, newObj)
+}
+
+func (w *watcher) OnDelete(obj interface{}) {
+	w.send(Deleted, obj)
+}
+
+func (w *watcher) send(eventType EventType, obj interface{}) {
+	runtimeObj, ok := obj.(runtime.Object)
+	if !ok {
+		return
+	}
+	clientObj, ok := runtimeObj.DeepCopyObject().(client.Object)
+	if !ok {
+		return
+	}
+	ev := Event{Type: eventType, Object: clientObj}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.stopped {
+		return
+	}
+	if w.block {
+		select {
+		case w.ch <- ev:
+		case <-w.done:
+		}
+		return
+	}
+	select {
+	case w.ch <- ev:
+	default:
+	}
+}
+
+// stop closes the channel once the pending sends are over. Closing done first releases
+// the sends blocked on a full channel.
+func (w *watcher) stop() {
+	w.once.Do(func() {
+		close(w.done)
+		w.mu.Lock()
+		w.stopped = true
+		close(w.ch)
+		w.mu.Unlock()
+	})
+}
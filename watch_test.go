@@ -0,0 +1,139 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// receiveEvents receives n events from ch and returns them as "<type> <namespace/name>".
+func receiveEvents(t *testing.T, ch <-chan Event, n int) []string {
+	t.Helper()
+
+	events := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		select {
+		case ev, open := <-ch:
+			if !open {
+				t.Fatalf("the channel was closed after %d events, want %d", i, n)
+			}
+			events = append(events, string(ev.Type)+" "+client.ObjectKeyFromObject(ev.Object).String())
+		default:
+			t.Fatalf("got %d events, want %d", i, n)
+		}
+	}
+	return events
+}
+
+// assertNoEvent fails the test if an event is pending in ch.
+func assertNoEvent(t *testing.T, ch <-chan Event) {
+	t.Helper()
+
+	select {
+	case ev, open := <-ch:
+		if open {
+			t.Errorf("got unexpected event %s %s", ev.Type, client.ObjectKeyFromObject(ev.Object))
+		}
+	default:
+	}
+}
+
+func TestWatch(t *testing.T) {
+	c := newTestCache(t)
+	mustAdd(t, c, deployment("default", "cached", nil))
+	ch, stop, err := c.Watch(&appsv1.Deployment{})
+	if err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+	defer stop()
+
+	mustAdd(t, c, deployment("default", "web", nil))
+	if err := c.Update(deployment("default", "web", map[string]string{"app": "web"})); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	if err := c.Delete(deployment("default", "web", nil)); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+
+	// unlike AddEventHandler, the objects cached before the watch are not replayed.
+	want := []string{"ADDED default/web", "MODIFIED default/web", "DELETED default/web"}
+	if got := receiveEvents(t, ch, len(want)); !slices.Equal(got, want) {
+		t.Errorf("got events %v, want %v", got, want)
+	}
+	assertNoEvent(t, ch)
+}
+
+func TestWatchEventsAreCopies(t *testing.T) {
+	c := newTestCache(t)
+	ch, stop, err := c.Watch(&appsv1.Deployment{})
+	if err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+	defer stop()
+
+	mustAdd(t, c, deployment("default", "web", map[string]string{"app": "web"}))
+	ev := <-ch
+	ev.Object.SetLabels(map[string]string{"app": "mutated"})
+
+	if got := listDeploymentKeys(t, c, client.MatchingLabels{"app": "web"}); !slices.Equal(got, []string{"default/web"}) {
+		t.Errorf("mutating an event object changed the cache, got %v", got)
+	}
+}
+
+func TestWatchStop(t *testing.T) {
+	c := newTestCache(t)
+	ch, stop, err := c.Watch(&appsv1.Deployment{})
+	if err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+
+	stop()
+	// stopping twice is a no-op.
+	stop()
+	mustAdd(t, c, deployment("default", "web", nil))
+
+	if ev, open := <-ch; open {
+		t.Errorf("got event %s %s after stop, want a closed channel", ev.Type, client.ObjectKeyFromObject(ev.Object))
+	}
+}
+
+func TestWatchDropsEventsOfFullChannel(t *testing.T) {
+	c := newTestCache(t)
+	ch, stop, err := c.Watch(&appsv1.Deployment{}, WatchBufferSize(1))
+	if err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+	defer stop()
+
+	mustAdd(t, c, deployment("default", "a", nil), deployment("default", "b", nil))
+
+	want := []string{"ADDED default/a"}
+	if got := receiveEvents(t, ch, 1); !slices.Equal(got, want) {
+		t.Errorf("got events %v, want %v", got, want)
+	}
+	assertNoEvent(t, ch)
+}
+
+func TestWatchUnregisterKind(t *testing.T) {
+	c := newTestCache(t)
+	mustAdd(t, c, deployment("default", "b", nil), deployment("default", "a", nil))
+	ch, stop, err := c.Watch(&appsv1.Deployment{})
+	if err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+	defer stop()
+
+	if err := c.UnregisterKind(&appsv1.Deployment{}); err != nil {
+		t.Fatalf("failed to unregister deployments: %v", err)
+	}
+
+	want := []string{"DELETED default/a", "DELETED default/b"}
+	if got := receiveEvents(t, ch, len(want)); !slices.Equal(got, want) {
+		t.Errorf("got events %v, want %v", got, want)
+	}
+	// the watcher is unregistered with the kind.
+	mustAdd(t, c, deployment("default", "c", nil))
+	assertNoEvent(t, ch)
+}